@@ -0,0 +1,106 @@
+package slidingwindow
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AdaptiveOption configures optional behaviours of an adaptive limiter.
+type AdaptiveOption func(*AdaptiveLimiter)
+
+// WithAdditiveIncrease sets how much the limit increases on each success.
+// The default is 1.
+func WithAdditiveIncrease(n int64) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		a.increase = n
+	}
+}
+
+// WithMultiplicativeDecrease sets the factor, within (0, 1), by which the
+// limit is multiplied on each failure. The default is 0.5.
+func WithMultiplicativeDecrease(factor float64) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		a.decrease = factor
+	}
+}
+
+// WithLimitBounds sets the bounds within which the limit adapts. By default,
+// the minimum is 1, and the maximum is the initial limit.
+func WithLimitBounds(min, max int64) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		a.min, a.max = min, max
+	}
+}
+
+// WithLatencyThreshold makes a success, whose latency exceeds threshold,
+// be treated as a failure. A non-positive threshold disables it, which is
+// the default.
+func WithLatencyThreshold(threshold time.Duration) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		a.latencyThreshold = threshold
+	}
+}
+
+// AdaptiveLimiter is a limiter whose limit auto-tunes by the feedback from
+// downstream, in the AIMD (additive-increase/multiplicative-decrease) way:
+// the limit increases by a constant on success, and decreases by a factor
+// on failure. Meanwhile, the sliding window keeps tracking the actual
+// throughput.
+type AdaptiveLimiter struct {
+	*Limiter
+
+	increase         int64
+	decrease         float64
+	min, max         int64
+	latencyThreshold time.Duration
+
+	mu sync.Mutex // Serializes the feedback.
+}
+
+// NewAdaptiveLimiter creates an adaptive limiter which adapts the limit of
+// lim, starting from its current limit. It returns ErrInvalidFactor if the
+// multiplicative decrease factor is not within (0, 1).
+func NewAdaptiveLimiter(lim *Limiter, opts ...AdaptiveOption) (*AdaptiveLimiter, error) {
+	a := &AdaptiveLimiter{
+		Limiter:  lim,
+		increase: 1,
+		decrease: 0.5,
+		min:      1,
+		max:      lim.Limit(),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	// Note that the comparison also rejects NaN.
+	if !(a.decrease > 0 && a.decrease < 1) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFactor, a.decrease)
+	}
+	return a, nil
+}
+
+// Feedback adapts the limit by the outcome of a request, along with its
+// latency.
+func (a *AdaptiveLimiter) Feedback(success bool, latency time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.latencyThreshold > 0 && latency > a.latencyThreshold {
+		success = false
+	}
+
+	limit := a.Limit()
+	if success {
+		limit += a.increase
+	} else {
+		limit = int64(float64(limit) * a.decrease)
+	}
+
+	if limit < a.min {
+		limit = a.min
+	} else if limit > a.max {
+		limit = a.max
+	}
+	a.SetLimit(limit)
+}
@@ -0,0 +1,63 @@
+package slidingwindow
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiter_Feedback(t *testing.T) {
+	lim, _ := NewLimiter(size, 100, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+	a, _ := NewAdaptiveLimiter(lim,
+		WithAdditiveIncrease(10),
+		WithLimitBounds(5, 100),
+		WithLatencyThreshold(time.Second),
+	)
+
+	steps := []struct {
+		name      string
+		success   bool
+		latency   time.Duration
+		times     int
+		wantLimit int64
+	}{
+		{"capped at the maximum", true, 0, 1, 100},
+		{"failures", false, 0, 2, 25},
+		{"floored at the minimum", false, 0, 10, 5},
+		{"successes", true, 0, 3, 35},
+		{"slow successes", true, 2 * time.Second, 1, 17},
+		{"recovered", true, 0, 20, 100},
+	}
+	for _, s := range steps {
+		for i := 0; i < s.times; i++ {
+			a.Feedback(s.success, s.latency)
+		}
+		if got := a.Limit(); got != s.wantLimit {
+			t.Errorf("%s: a.Limit() = %d, want: %d", s.name, got, s.wantLimit)
+		}
+	}
+
+	// The adapted limit takes effect on the decisions.
+	for i := 0; i < 10; i++ {
+		a.Feedback(false, 0)
+	}
+	if a.AllowN(t0, 6) {
+		t.Errorf("a.AllowN(%v, 6) = true, want: false", t0)
+	}
+}
+
+func TestNewAdaptiveLimiter_Invalid(t *testing.T) {
+	lim, _ := NewLimiter(size, 100, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	for _, factor := range []float64{0, -0.5, 1, 1.5, math.NaN()} {
+		_, err := NewAdaptiveLimiter(lim, WithMultiplicativeDecrease(factor))
+		if !errors.Is(err, ErrInvalidFactor) {
+			t.Errorf("NewAdaptiveLimiter(%v) err: %v, want: %v", factor, err, ErrInvalidFactor)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// Aligner determines the boundaries of the windows.
+//
+// By default, windows are aligned by truncating the time to a multiple
+// of the window size. A custom aligner is useful when the durations of
+// windows vary, e.g. calendar months.
+type Aligner interface {
+	// Align returns the start boundary of the window containing t.
+	Align(t time.Time) time.Time
+
+	// Next returns the start boundary of the window right after the one
+	// starting at start, which is also the end boundary of the latter.
+	Next(start time.Time) time.Time
+}
+
+// WithAligner sets the aligner used to determine the window boundaries.
+//
+// Note that once a custom aligner is set, the weight of the previous window
+// is calculated from the actual duration of the current window, and the
+// size given to NewLimiter is no longer used by the limiter itself.
+func WithAligner(a Aligner) Option {
+	return func(lim *Limiter) {
+		lim.aligner = a
+	}
+}
+
+// sizeAligner is the default aligner, which aligns windows to multiples
+// of a fixed size.
+type sizeAligner struct {
+	size time.Duration
+}
+
+func (a sizeAligner) Align(t time.Time) time.Time {
+	return t.Truncate(a.size)
+}
+
+func (a sizeAligner) Next(start time.Time) time.Time {
+	return start.Add(a.size)
+}
+
+// WithStartQuantum makes the window boundaries snap to multiples of
+// quantum, which helps limiters of slightly different sizes share aligned
+// boundaries.
+//
+// Each boundary is the default one (i.e. a multiple of size) rounded down
+// to a multiple of quantum, thus the duration of an individual window may
+// differ from size by less than quantum, while the average duration is
+// still size. A quantum that is not positive or exceeds size is ignored.
+func WithStartQuantum(quantum time.Duration) Option {
+	return func(lim *Limiter) {
+		if quantum <= 0 || quantum > lim.size {
+			return
+		}
+		lim.aligner = quantumAligner{size: lim.size, quantum: quantum}
+	}
+}
+
+// quantumAligner aligns windows to multiples of size, rounded down to
+// multiples of quantum.
+type quantumAligner struct {
+	size    time.Duration
+	quantum time.Duration
+}
+
+func (a quantumAligner) Align(t time.Time) time.Time {
+	s := t.Truncate(a.size)
+	if next := s.Add(a.size).Truncate(a.quantum); !t.Before(next) {
+		return next
+	}
+	return s.Truncate(a.quantum)
+}
+
+func (a quantumAligner) Next(start time.Time) time.Time {
+	// Since quantum does not exceed size, the multiple of size from which
+	// start is derived is the smallest one not before start.
+	s := start.Add(a.size - 1).Truncate(a.size)
+	return s.Add(a.size).Truncate(a.quantum)
+}
+
+// CalendarAligner aligns windows to calendar months, or calendar days if
+// Days is positive, in the given location (UTC by default). Each window
+// spans the given number of months (or days), e.g. 1 for monthly windows
+// and 3 for quarterly windows.
+//
+// Since the boundaries are local midnights, a window may be shorter or
+// longer than usual across a DST transition, e.g. a day of 23 hours.
+type CalendarAligner struct {
+	Months   int
+	Days     int
+	Location *time.Location
+}
+
+func (a CalendarAligner) months() int {
+	if a.Months <= 0 {
+		return 1
+	}
+	return a.Months
+}
+
+func (a CalendarAligner) location() *time.Location {
+	if a.Location == nil {
+		return time.UTC
+	}
+	return a.Location
+}
+
+func (a CalendarAligner) Align(t time.Time) time.Time {
+	loc := a.location()
+	t = t.In(loc)
+
+	if a.Days > 0 {
+		// The index of the day counted from the Unix epoch, regardless
+		// of the location.
+		y, m, d := t.Date()
+		i := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+		r := ((i % int64(a.Days)) + int64(a.Days)) % int64(a.Days)
+		return time.Date(y, m, d-int(r), 0, 0, 0, 0, loc)
+	}
+
+	m := a.months()
+
+	// The index of the month counted from January of year 0.
+	i := t.Year()*12 + int(t.Month()) - 1
+	i -= ((i % m) + m) % m
+
+	return time.Date(i/12, time.Month(i%12+1), 1, 0, 0, 0, 0, loc)
+}
+
+func (a CalendarAligner) Next(start time.Time) time.Time {
+	// Adding dates in the location keeps the boundaries at local midnights.
+	start = start.In(a.location())
+	if a.Days > 0 {
+		return start.AddDate(0, 0, a.Days)
+	}
+	return start.AddDate(0, a.months(), 0)
+}
+
+// WithLocation sets the location in which the calendar-aligned windows (by
+// CalendarAligner) are aligned, e.g. to reset a daily quota at the local
+// midnight. It overrides the Location of the CalendarAligner, whichever
+// option comes first, and has no effect on the other aligners.
+func WithLocation(loc *time.Location) Option {
+	return func(lim *Limiter) {
+		lim.location = loc
+	}
+}
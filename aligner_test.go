@@ -0,0 +1,153 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendarAligner(t *testing.T) {
+	cases := []struct {
+		a     CalendarAligner
+		t     time.Time
+		start time.Time
+		next  time.Time
+	}{
+		{
+			CalendarAligner{Months: 1},
+			time.Date(2026, 2, 28, 23, 59, 59, 0, time.UTC),
+			time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			CalendarAligner{Months: 3},
+			time.Date(2026, 5, 20, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			CalendarAligner{},
+			time.Date(2026, 12, 31, 12, 0, 0, 0, time.UTC),
+			time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			CalendarAligner{Days: 1},
+			time.Date(2026, 2, 28, 23, 59, 59, 0, time.UTC),
+			time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// Day 20510 since the Unix epoch is a multiple of 7.
+			CalendarAligner{Days: 7},
+			time.Date(2026, 2, 25, 12, 0, 0, 0, time.UTC),
+			time.Date(2026, 2, 26, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -7),
+			time.Date(2026, 2, 26, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, c := range cases {
+		start := c.a.Align(c.t)
+		if !start.Equal(c.start) {
+			t.Errorf("%+v.Align(%v) = %v, want: %v", c.a, c.t, start, c.start)
+		}
+		if next := c.a.Next(start); !next.Equal(c.next) {
+			t.Errorf("%+v.Next(%v) = %v, want: %v", c.a, start, next, c.next)
+		}
+	}
+}
+
+func TestLimiter_CalendarAligner_AllowN(t *testing.T) {
+	lim, _ := NewLimiter(30*24*time.Hour, 100, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithAligner(CalendarAligner{Months: 1}))
+
+	feb := time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC)
+	if ok := lim.AllowN(feb, 40); !ok {
+		t.Fatalf("lim.AllowN(%v, 40) = false, want: true", feb)
+	}
+
+	// February has 28 days, the event still lands in the February window.
+	endOfFeb := time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC)
+	if got := lim.Count(endOfFeb); got != 40 {
+		t.Errorf("lim.Count(%v) = %d, want: 40", endOfFeb, got)
+	}
+
+	// March has 31 days, so 15.5 days into March is halfway through.
+	midOfMar := time.Date(2026, 3, 16, 12, 0, 0, 0, time.UTC)
+	if got := lim.Count(midOfMar); got != 20 {
+		t.Errorf("lim.Count(%v) = %d, want: 20", midOfMar, got)
+	}
+
+	// The window after next no longer inherits any count.
+	may := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	if got := lim.Count(may); got != 0 {
+		t.Errorf("lim.Count(%v) = %d, want: 0", may, got)
+	}
+}
+
+func TestLimiter_WithLocation(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time.LoadLocation() err: %v", err)
+	}
+
+	lim, _ := NewLimiter(24*time.Hour, 100, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithLocation(loc), WithAligner(CalendarAligner{Days: 1}))
+
+	// On 2026-03-08, DST starts at 2:00, thus the day has only 23 hours.
+	dstDay := time.Date(2026, 3, 8, 0, 0, 0, 0, loc)
+	nextDay := time.Date(2026, 3, 9, 0, 0, 0, 0, loc)
+	if got := nextDay.Sub(dstDay); got != 23*time.Hour {
+		t.Fatalf("the DST day lasts %v, want: 23h", got)
+	}
+
+	lim.AllowN(time.Date(2026, 3, 7, 12, 0, 0, 0, loc), 40)
+
+	// The window starts at the local midnight, instead of in UTC.
+	noon := time.Date(2026, 3, 8, 12, 0, 0, 0, loc)
+	lim.AllowN(noon, 10)
+	if start := lim.CurrentWindowForTest().Start(); !start.Equal(dstDay) {
+		t.Errorf("the window starts at %v, want: %v", start, dstDay)
+	}
+
+	// 11.5 hours into the 23-hour day is halfway through.
+	if got := lim.Count(dstDay.Add(23 * time.Hour / 2)); got != 40/2+10 {
+		t.Errorf("lim.Count() = %d, want: %d", got, 40/2+10)
+	}
+
+	// The next window starts at the next local midnight, 23 hours later.
+	lim.AllowN(nextDay, 1)
+	if start := lim.CurrentWindowForTest().Start(); !start.Equal(nextDay) {
+		t.Errorf("the window starts at %v, want: %v", start, nextDay)
+	}
+	if got := lim.Count(nextDay); got != 10+1 {
+		t.Errorf("lim.Count(%v) = %d, want: %d", nextDay, got, 10+1)
+	}
+}
+
+func TestLimiter_WithStartQuantum(t *testing.T) {
+	quantum := time.Minute
+	lim, _ := NewLimiter(61*time.Second, 100, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithStartQuantum(quantum))
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 1000; i++ {
+		now := start.Add(time.Duration(i) * 7 * time.Second)
+		lim.AllowN(now, 0)
+
+		curr, prev := lim.curr.Start(), lim.prev.Start()
+		if !curr.Truncate(quantum).Equal(curr) || !prev.Truncate(quantum).Equal(prev) {
+			t.Fatalf("starts (%v, %v) are not multiples of %v", prev, curr, quantum)
+		}
+
+		// The windows are contiguous and contain now.
+		next := lim.aligner.Next(curr)
+		if now.Before(curr) || !now.Before(next) {
+			t.Fatalf("now %v is not in window [%v, %v)", now, curr, next)
+		}
+		if got := lim.aligner.Next(prev); !got.Equal(curr) {
+			t.Fatalf("Next(%v) = %v, want: %v", prev, got, curr)
+		}
+	}
+}
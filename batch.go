@@ -0,0 +1,186 @@
+package slidingwindow
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BatchAdd is one of the adds in a batch.
+type BatchAdd struct {
+	Key   string
+	Start int64
+	Delta int64
+}
+
+// BatchAdder is a datastore which can also perform a batch of adds in
+// a single round-trip (e.g. leveraging Redis pipelining).
+type BatchAdder interface {
+	Datastore
+
+	// AddBatch performs all the adds, and returns the new counts in order.
+	AddBatch(adds []BatchAdd) ([]int64, error)
+}
+
+type batchKey struct {
+	key   string
+	start int64
+}
+
+type batchEntry struct {
+	delta int64
+	count int64
+	err   error
+	done  chan struct{}
+}
+
+// BatchDatastore is a datastore which coalesces the pending adds of all the
+// windows (typically, thousands of limiters on a node) sharing it, and
+// performs them in a single round-trip on every interval, instead of one
+// round-trip per add. The adds with the same key and start are summed
+// into one.
+//
+// Add blocks until the next batch is performed, thus BatchDatastore works
+// best with NonblockingSynchronizer. Get is not batched.
+//
+// The adds are only batched while the batching loop is running, i.e. after
+// Start and before Stop. Otherwise, they are performed directly.
+type BatchDatastore struct {
+	store    BatchAdder
+	interval time.Duration
+
+	mu       sync.Mutex
+	pending  map[batchKey]*batchEntry
+	batching bool // Whether the batching loop is running.
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopC     chan struct{}
+	exitC     chan struct{}
+}
+
+// NewBatchDatastore creates a datastore which batches the adds to store on
+// every interval, which must be positive. The batching loop is started by
+// Start.
+func NewBatchDatastore(store BatchAdder, interval time.Duration) (*BatchDatastore, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInterval, interval)
+	}
+	return &BatchDatastore{
+		store:    store,
+		interval: interval,
+		pending:  make(map[batchKey]*batchEntry),
+		stopC:    make(chan struct{}),
+		exitC:    make(chan struct{}),
+	}, nil
+}
+
+// Start starts the batching loop. It's a no-op if the loop has ever been
+// started, or stopped.
+func (d *BatchDatastore) Start() {
+	d.startOnce.Do(func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		select {
+		case <-d.stopC:
+			// Already stopped.
+			return
+		default:
+		}
+		d.batching = true
+		go d.batchLoop()
+	})
+}
+
+// Stop stops the batching loop, after performing the remaining adds.
+// Since then, the adds are no longer batched. Stop is idempotent.
+func (d *BatchDatastore) Stop() {
+	d.stopOnce.Do(func() {
+		d.mu.Lock()
+		started := d.batching
+		close(d.stopC)
+		d.mu.Unlock()
+
+		if started {
+			<-d.exitC
+		}
+	})
+}
+
+func (d *BatchDatastore) batchLoop() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.flush(false)
+		case <-d.stopC:
+			d.flush(true)
+			close(d.exitC)
+			return
+		}
+	}
+}
+
+// flush performs all the pending adds in a batch.
+func (d *BatchDatastore) flush(final bool) {
+	d.mu.Lock()
+	pending := d.pending
+	d.pending = make(map[batchKey]*batchEntry)
+	if final {
+		d.batching = false
+	}
+	d.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	adds := make([]BatchAdd, 0, len(pending))
+	entries := make([]*batchEntry, 0, len(pending))
+	for k, e := range pending {
+		adds = append(adds, BatchAdd{Key: k.key, Start: k.start, Delta: e.delta})
+		entries = append(entries, e)
+	}
+
+	counts, err := d.store.AddBatch(adds)
+	if err == nil && len(counts) != len(adds) {
+		err = fmt.Errorf("slidingwindow: AddBatch returned %d counts for %d adds", len(counts), len(adds))
+	}
+	for i, e := range entries {
+		if err != nil {
+			e.err = err
+		} else {
+			e.count = counts[i]
+		}
+		close(e.done)
+	}
+}
+
+// Add adds delta to the count of the window represented by start in the
+// next batch, and returns the new count after the batch is performed.
+func (d *BatchDatastore) Add(key string, start, delta int64) (int64, error) {
+	d.mu.Lock()
+	if !d.batching {
+		d.mu.Unlock()
+		return d.store.Add(key, start, delta)
+	}
+	k := batchKey{key: key, start: start}
+	e, ok := d.pending[k]
+	if !ok {
+		e = &batchEntry{done: make(chan struct{})}
+		d.pending[k] = e
+	}
+	e.delta += delta
+	d.mu.Unlock()
+
+	<-e.done
+	return e.count, e.err
+}
+
+// Get returns the count of the window represented by start.
+func (d *BatchDatastore) Get(key string, start int64) (int64, error) {
+	return d.store.Get(key, start)
+}
@@ -0,0 +1,156 @@
+package slidingwindow
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// batchMemDatastore is a MemDatastore which also counts the round-trips.
+type batchMemDatastore struct {
+	*MemDatastore
+	roundTrips int64
+}
+
+func (d *batchMemDatastore) Add(key string, start, delta int64) (int64, error) {
+	atomic.AddInt64(&d.roundTrips, 1)
+	return d.MemDatastore.Add(key, start, delta)
+}
+
+func (d *batchMemDatastore) AddBatch(adds []BatchAdd) ([]int64, error) {
+	atomic.AddInt64(&d.roundTrips, 1)
+	counts := make([]int64, len(adds))
+	for i, a := range adds {
+		counts[i], _ = d.MemDatastore.Add(a.Key, a.Start, a.Delta)
+	}
+	return counts, nil
+}
+
+func TestBatchDatastore_Add(t *testing.T) {
+	store := &batchMemDatastore{MemDatastore: newMemDatastore()}
+	d, _ := NewBatchDatastore(store, 10*time.Millisecond)
+	d.Start()
+
+	const (
+		keys  = 3
+		adds  = 100
+		start = int64(1)
+	)
+
+	var wg sync.WaitGroup
+	for k := 0; k < keys; k++ {
+		key := fmt.Sprintf("key%d", k)
+		for i := 0; i < adds; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := d.Add(key, start, 2); err != nil {
+					t.Errorf("d.Add() err: %v", err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	d.Stop()
+
+	for k := 0; k < keys; k++ {
+		key := fmt.Sprintf("key%d", k)
+		if count, _ := d.Get(key, start); count != 2*adds {
+			t.Errorf("count of %s = %d, want: %d", key, count, 2*adds)
+		}
+	}
+	if n := atomic.LoadInt64(&store.roundTrips); n >= keys*adds {
+		t.Errorf("round-trips = %d, want: < %d", n, keys*adds)
+	}
+
+	// The adds are no longer batched once stopped.
+	if count, _ := d.Add("key0", start, 1); count != 2*adds+1 {
+		t.Errorf("count of key0 = %d, want: %d", count, 2*adds+1)
+	}
+}
+
+// shortBatchDatastore is a batchMemDatastore whose AddBatch returns one
+// count fewer than the adds.
+type shortBatchDatastore struct {
+	*batchMemDatastore
+}
+
+func (d shortBatchDatastore) AddBatch(adds []BatchAdd) ([]int64, error) {
+	counts, err := d.batchMemDatastore.AddBatch(adds)
+	return counts[:len(counts)-1], err
+}
+
+func TestNewBatchDatastore_Invalid(t *testing.T) {
+	store := &batchMemDatastore{MemDatastore: newMemDatastore()}
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := NewBatchDatastore(store, interval); !errors.Is(err, ErrInvalidInterval) {
+			t.Errorf("NewBatchDatastore(%v) err: %v, want: %v", interval, err, ErrInvalidInterval)
+		}
+	}
+}
+
+func TestBatchDatastore_StartStop(t *testing.T) {
+	store := &batchMemDatastore{MemDatastore: newMemDatastore()}
+	d, _ := NewBatchDatastore(store, time.Millisecond)
+
+	// The adds are performed directly before Start.
+	if count, err := d.Add("key", 1, 2); err != nil || count != 2 {
+		t.Errorf("d.Add() = (%d, %v), want: (2, nil)", count, err)
+	}
+
+	d.Start()
+	d.Start() // no-op
+	d.Stop()
+	d.Stop() // no-op
+
+	if count, err := d.Add("key", 1, 2); err != nil || count != 4 {
+		t.Errorf("d.Add() = (%d, %v), want: (4, nil)", count, err)
+	}
+
+	// Stop without Start never blocks.
+	d, _ = NewBatchDatastore(store, time.Millisecond)
+	d.Stop()
+}
+
+func TestBatchDatastore_ShortCounts(t *testing.T) {
+	store := shortBatchDatastore{&batchMemDatastore{MemDatastore: newMemDatastore()}}
+	d, _ := NewBatchDatastore(store, time.Millisecond)
+	d.Start()
+	defer d.Stop()
+
+	if _, err := d.Add("key", 1, 2); err == nil {
+		t.Errorf("d.Add() err: nil, want: non-nil")
+	}
+}
+
+func BenchmarkBatchDatastore_Add(b *testing.B) {
+	const keys = 1000
+
+	run := func(b *testing.B, store *batchMemDatastore, ds Datastore) {
+		// Simulate many windows syncing concurrently.
+		b.SetParallelism(100)
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				ds.Add(fmt.Sprintf("key%d", i%keys), 1, 1)
+				i++
+			}
+		})
+		b.ReportMetric(float64(atomic.LoadInt64(&store.roundTrips))/float64(b.N), "roundtrips/op")
+	}
+
+	b.Run("direct", func(b *testing.B) {
+		store := &batchMemDatastore{MemDatastore: newMemDatastore()}
+		run(b, store, store)
+	})
+	b.Run("batch", func(b *testing.B) {
+		store := &batchMemDatastore{MemDatastore: newMemDatastore()}
+		d, _ := NewBatchDatastore(store, time.Millisecond)
+		d.Start()
+		defer d.Stop()
+		run(b, store, d)
+	})
+}
@@ -0,0 +1,90 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// WithBurstThreshold makes AllowN exempt small clustered bursts from being
+// counted, to avoid penalizing legitimate bursts.
+//
+// A burst starts with the first admitted event after the previous burst has
+// ended, and lasts for the duration within. The events within a burst are
+// counted only if their total count exceeds n, in which case all of them
+// (including the earlier ones) are counted from then on. Thus sustained
+// traffic, which always makes up large bursts, is fully counted. The denied
+// events never make up a burst.
+//
+// Note that exempt events are still denied if the limit has been reached.
+// A non-positive n or within disables the threshold, which is the default.
+func WithBurstThreshold(n int64, within time.Duration) Option {
+	return func(lim *Limiter) {
+		if n <= 0 || within <= 0 {
+			lim.burst = nil
+			return
+		}
+		lim.burst = &burstFilter{threshold: n, within: within}
+	}
+}
+
+// burstFilter detects bursts which exceed a threshold.
+//
+// All methods are safe to call on a nil burstFilter, which filters nothing.
+type burstFilter struct {
+	threshold int64
+	within    time.Duration
+
+	start    time.Time // The start time of the current burst.
+	count    int64     // The total count of events within the current burst.
+	counting bool      // Whether the current burst has exceeded the threshold.
+}
+
+// Filter returns how many of the n events happening at time now should be
+// counted, given the events admitted within the current burst so far. It
+// records nothing, which is left to Commit once the events are admitted.
+func (b *burstFilter) Filter(now time.Time, n int64) int64 {
+	if b == nil {
+		return n
+	}
+
+	count, counting := b.current(now)
+	count += n
+
+	switch {
+	case counting:
+		return n
+	case count > b.threshold:
+		// Count all the events within the burst, including the exempt ones.
+		return count
+	default:
+		return 0
+	}
+}
+
+// Commit records n admitted events happened at time now.
+func (b *burstFilter) Commit(now time.Time, n int64) {
+	if b == nil {
+		return
+	}
+
+	if b.ended(now) {
+		// Start a new burst.
+		b.start, b.count, b.counting = now, 0, false
+	}
+	b.count += n
+	b.counting = b.counting || b.count > b.threshold
+}
+
+// current returns the count and the counting state of the burst which
+// the events happening at time now belong to.
+func (b *burstFilter) current(now time.Time) (count int64, counting bool) {
+	if b.ended(now) {
+		// A new burst starts.
+		return 0, false
+	}
+	return b.count, b.counting
+}
+
+// ended reports whether the current burst has ended by time now.
+func (b *burstFilter) ended(now time.Time) bool {
+	return b.start.IsZero() || !now.Before(b.start.Add(b.within))
+}
@@ -0,0 +1,86 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_WithBurstThreshold(t *testing.T) {
+	lim, _ := NewLimiter(size, 100, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithBurstThreshold(3, 2*d))
+
+	// A small burst is exempt.
+	lim.AllowN(t1, 2)
+	lim.AllowN(t2, 1)
+	if got := lim.Count(t3); got != 0 {
+		t.Errorf("lim.Count(%v) = %d, want: 0", t3, got)
+	}
+
+	// A large burst is counted, including the events before it exceeds
+	// the threshold.
+	lim.AllowN(t3, 2)
+	lim.AllowN(t3, 1)
+	if got := lim.Count(t3); got != 0 {
+		t.Errorf("lim.Count(%v) = %d, want: 0", t3, got)
+	}
+	lim.AllowN(t4, 1)
+	if got := lim.Count(t4); got != 4 {
+		t.Errorf("lim.Count(%v) = %d, want: 4", t4, got)
+	}
+	lim.AllowN(t4, 1)
+	if got := lim.Count(t4); got != 5 {
+		t.Errorf("lim.Count(%v) = %d, want: 5", t4, got)
+	}
+
+	// Sustained traffic makes up large bursts, and is always counted.
+	for i := 0; i < 4; i++ {
+		now := t10.Add(d * 2 * time.Duration(i))
+		lim.AllowN(now, 2)
+		lim.AllowN(now.Add(d), 2)
+	}
+	if got, want := lim.Count(t18), int64(5/5+16); got != want {
+		t.Errorf("lim.Count(%v) = %d, want: %d", t18, got, want)
+	}
+}
+
+func TestLimiter_WithBurstThreshold_atLimit(t *testing.T) {
+	lim, _ := NewLimiter(size, 2, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithBurstThreshold(3, 2*d))
+
+	// Reach the limit exactly.
+	lim.SetCount(t1, 2)
+
+	// A small burst is exempt, but still denied.
+	if ok := lim.AllowN(t4, 1); ok {
+		t.Errorf("lim.AllowN(%v, 1) = %v, want: false", t4, ok)
+	}
+	if got := lim.Count(t4); got != 2 {
+		t.Errorf("lim.Count(%v) = %d, want: 2", t4, got)
+	}
+}
+
+func TestLimiter_WithBurstThreshold_deniedCrossing(t *testing.T) {
+	lim, _ := NewLimiter(size, 4, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithBurstThreshold(3, 2*d))
+
+	// A small burst is exempt.
+	for i := 0; i < 3; i++ {
+		lim.AllowN(t1, 1)
+	}
+
+	// Crossing the threshold would count 5 events, which are denied.
+	if ok := lim.AllowN(t1, 2); ok {
+		t.Errorf("lim.AllowN(%v, 2) = %v, want: false", t1, ok)
+	}
+
+	// The exempt events are still counted once the burst is large enough.
+	if ok := lim.AllowN(t2, 1); !ok {
+		t.Errorf("lim.AllowN(%v, 1) = %v, want: true", t2, ok)
+	}
+	if got, want := lim.Count(t2), lim.Total(); got != want {
+		t.Errorf("lim.Count(%v) = %d, want: %d", t2, got, want)
+	}
+}
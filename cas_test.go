@@ -0,0 +1,102 @@
+package slidingwindow
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// casMemDatastore is a MemDatastore which also supports compare-and-add.
+// The first conflicts calls of CompareAndAdd are each preceded by a
+// concurrent update of one event from another node.
+type casMemDatastore struct {
+	*MemDatastore
+
+	mu        sync.Mutex
+	conflicts int
+	calls     int
+}
+
+func newCASMemDatastore(conflicts int) *casMemDatastore {
+	return &casMemDatastore{MemDatastore: newMemDatastore(), conflicts: conflicts}
+}
+
+func (d *casMemDatastore) CompareAndAdd(key string, start, expected, delta int64) (int64, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.calls++
+	if d.conflicts > 0 {
+		d.conflicts--
+		d.MemDatastore.Add(key, start, 1) // nolint:errcheck
+	}
+
+	count, _ := d.MemDatastore.Get(key, start)
+	if count != expected {
+		return count, false, nil
+	}
+	count, err := d.MemDatastore.Add(key, start, delta)
+	return count, err == nil, err
+}
+
+func TestSyncHelper_CompareAndAdd(t *testing.T) {
+	cases := []struct {
+		name      string
+		conflicts int
+		wantCalls int
+		wantCount int64
+	}{
+		{"no conflict", 0, 1, 7},
+		{"retried", 2, 3, 9},
+		{"fallback", maxCASRetries + 5, maxCASRetries + 1, 7 + maxCASRetries + 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := newCASMemDatastore(c.conflicts)
+			store.MemDatastore.Add("test", 0, 4) // nolint:errcheck
+
+			h := newSyncHelper(store, 0)
+			resp, err := h.Sync(SyncRequest{Key: "test", Count: 7, Changes: 3})
+			if err != nil {
+				t.Fatalf("Sync: err: %v", err)
+			}
+
+			if store.calls != c.wantCalls {
+				t.Errorf("store.calls = %d, want: %d", store.calls, c.wantCalls)
+			}
+			count, _ := store.Get("test", 0)
+			if count != c.wantCount {
+				t.Errorf("store.Get() = %d, want: %d", count, c.wantCount)
+			}
+			if want := c.wantCount - 7; resp.OtherChanges != want {
+				t.Errorf("resp.OtherChanges = %d, want: %d", resp.OtherChanges, want)
+			}
+		})
+	}
+}
+
+func TestLimiter_CASDatastore(t *testing.T) {
+	size := time.Hour
+	now := time.Now().Truncate(size)
+
+	store := newCASMemDatastore(1)
+	newLimiter := func() *Limiter {
+		lim, _ := NewLimiter(size, 100, func() (Window, StopFunc) {
+			return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+		})
+		return lim
+	}
+	lim1, lim2 := newLimiter(), newLimiter()
+
+	lim1.AllowN(now, 5)
+	lim2.AllowN(now, 3)
+	lim1.AllowN(now, 0)
+
+	// The concurrent update injected by the datastore is counted as well.
+	if count, _ := store.Get("test", now.UnixNano()); count != 9 {
+		t.Errorf("store.Get() = %d, want: 9", count)
+	}
+	if got := lim1.Count(now); got != 9 {
+		t.Errorf("lim1.Count(%v) = %d, want: 9", now, got)
+	}
+}
@@ -0,0 +1,70 @@
+package slidingwindow
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultSize is the window size used when Config.Size is zero.
+const DefaultSize = time.Second
+
+// Config holds all the settings of a limiter, which is an alternative to
+// the positional parameters of NewLimiter.
+type Config struct {
+	// Size is the time duration of one window. Defaults to DefaultSize.
+	Size time.Duration
+
+	// Limit is the maximum events permitted to happen during one window
+	// size. Note that a zero Limit permits no events at all.
+	Limit int64
+
+	// NewWindow creates the current window of the limiter. If nil, a
+	// SyncWindow is created when Datastore is set, or a LocalWindow
+	// otherwise.
+	NewWindow NewWindow
+
+	// Key, Datastore and SyncInterval configure the SyncWindow, which
+	// syncs with Datastore every SyncInterval in a non-blocking mode.
+	// They are ignored if NewWindow is set.
+	Key          string
+	Datastore    Datastore
+	SyncInterval time.Duration
+
+	// Options configure the optional behaviours of the limiter.
+	Options []Option
+}
+
+// New creates a new limiter from the given config, and returns a function
+// to stop the possible sync behaviour within the current window.
+func New(cfg Config) (*Limiter, StopFunc, error) {
+	if cfg.Size == 0 {
+		cfg.Size = DefaultSize
+	}
+
+	if cfg.Size < 0 {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSize, cfg.Size)
+	}
+	if cfg.Limit < 0 {
+		return nil, nil, fmt.Errorf("%w: %d", ErrInvalidLimit, cfg.Limit)
+	}
+	if cfg.SyncInterval < 0 {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidInterval, cfg.SyncInterval)
+	}
+
+	newWindow := cfg.NewWindow
+	if newWindow == nil {
+		newWindow = func() (Window, StopFunc) {
+			if cfg.Datastore == nil {
+				return NewLocalWindow()
+			}
+			return NewSyncWindow(cfg.Key, NewNonblockingSynchronizer(cfg.Datastore, cfg.SyncInterval))
+		}
+	}
+
+	lim, stop := NewLimiter(cfg.Size, cfg.Limit, newWindow, cfg.Options...)
+	if err := lim.weightFunc.validate(); err != nil {
+		stop()
+		return nil, nil, err
+	}
+	return lim, stop, nil
+}
@@ -0,0 +1,82 @@
+package slidingwindow
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNew_ZeroConfig(t *testing.T) {
+	lim, stop, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New() err: %v", err)
+	}
+	defer stop()
+
+	if got := lim.Size(); got != DefaultSize {
+		t.Errorf("lim.Size() = %v, want: %v", got, DefaultSize)
+	}
+	if got := lim.Limit(); got != 0 {
+		t.Errorf("lim.Limit() = %d, want: 0", got)
+	}
+	if _, ok := lim.curr.(*LocalWindow); !ok {
+		t.Errorf("lim.curr is %T, want: *LocalWindow", lim.curr)
+	}
+}
+
+func TestNew_FullConfig(t *testing.T) {
+	store := newMemDatastore()
+	lim, stop, err := New(Config{
+		Size:         size,
+		Limit:        limit,
+		Key:          "test",
+		Datastore:    store,
+		SyncInterval: 0,
+		Options:      []Option{WithCountCache(d)},
+	})
+	if err != nil {
+		t.Fatalf("New() err: %v", err)
+	}
+	defer stop()
+
+	if _, ok := lim.curr.(*SyncWindow); !ok {
+		t.Errorf("lim.curr is %T, want: *SyncWindow", lim.curr)
+	}
+	if lim.countCache == nil {
+		t.Errorf("lim.countCache is nil")
+	}
+
+	// The events are synced to the datastore eventually.
+	lim.AllowN(t0, 3)
+	for i := 0; i < 100; i++ {
+		if got, _ := store.Get("test", t0.UnixNano()); got == 3 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+		lim.AllowN(t0, 0)
+	}
+	t.Errorf("events are not synced to the datastore")
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	cases := []struct {
+		cfg Config
+		err error
+	}{
+		{Config{Size: -time.Second}, ErrInvalidSize},
+		{Config{Limit: -1}, ErrInvalidLimit},
+		{Config{SyncInterval: -time.Second}, ErrInvalidInterval},
+		{Config{Options: []Option{WithWeightFunc(func(fraction float64) float64 {
+			return 2 - 4*fraction
+		})}}, ErrInvalidWeight},
+		{Config{Options: []Option{WithWeightFunc(func(fraction float64) float64 {
+			return math.NaN()
+		})}}, ErrInvalidWeight},
+	}
+	for _, c := range cases {
+		if _, _, err := New(c.cfg); !errors.Is(err, c.err) {
+			t.Errorf("New(%+v) err: %v, want: %v", c.cfg, err, c.err)
+		}
+	}
+}
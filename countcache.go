@@ -0,0 +1,66 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// WithCountCache makes Count cache its result within each time quantum,
+// which benefits read-heavy workloads such as metrics scrapers.
+//
+// The cache is invalidated whenever the windows are changed (e.g. by AllowN)
+// or the current window rolls over. Otherwise, Count may return a result
+// computed at most one quantum earlier. A non-positive quantum disables
+// the cache, which is the default.
+func WithCountCache(quantum time.Duration) Option {
+	return func(lim *Limiter) {
+		if quantum <= 0 {
+			lim.countCache = nil
+			return
+		}
+		lim.countCache = &countCache{quantum: quantum}
+	}
+}
+
+// countCache holds the latest result of Count.
+//
+// All methods are safe to call on a nil countCache, which caches nothing.
+type countCache struct {
+	quantum time.Duration
+
+	valid bool
+	gen   uint64
+	// The cached count is valid during [from, until).
+	from  time.Time
+	until time.Time
+	count int64
+}
+
+// Get returns the cached count for time now and the limiter's generation
+// gen, if any.
+func (c *countCache) Get(now time.Time, gen uint64) (int64, bool) {
+	if c == nil || !c.valid || c.gen != gen {
+		return 0, false
+	}
+	if now.Before(c.from) || !now.Before(c.until) {
+		return 0, false
+	}
+	return c.count, true
+}
+
+// Set caches count computed at time now for the limiter's generation gen.
+// The cache expires at the end of the quantum, or at windowEnd when the
+// current window rolls over, whichever comes first.
+func (c *countCache) Set(now, windowEnd time.Time, gen uint64, count int64) {
+	if c == nil {
+		return
+	}
+
+	c.valid = true
+	c.gen = gen
+	c.from = now.Truncate(c.quantum)
+	c.until = c.from.Add(c.quantum)
+	if windowEnd.Before(c.until) {
+		c.until = windowEnd
+	}
+	c.count = count
+}
@@ -0,0 +1,60 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_WithCountCache(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithCountCache(5*d))
+
+	// prev-window: [t0, t0 + 1s), count: 5
+	// curr-window: [t10, t10 + 1s), count: 0
+	lim.AllowN(t0, 5)
+
+	cases := []struct {
+		now   time.Time
+		n     int64
+		count int64
+	}{
+		{t10, 0, 5},
+		{t10.Add(d / 2), 0, 5}, // cached
+		{t12, 1, 5},            // invalidated by AllowN
+		{t13, 0, 5},            // cached
+		{t14, 1, 5},            // invalidated by AllowN
+		{t15, 0, 4},            // new quantum
+		{t18, 0, 4},            // cached
+		{t0.Add(20 * d), 0, 2}, // invalidated by rollover
+	}
+	for _, c := range cases {
+		if c.n > 0 {
+			lim.AllowN(c.now, c.n)
+		}
+		if got := lim.Count(c.now); got != c.count {
+			t.Errorf("lim.Count(%v) = %d, want: %d", c.now, got, c.count)
+		}
+	}
+}
+
+func benchmarkLimiterCount(b *testing.B, opts ...Option) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, opts...)
+	lim.AllowN(t0, 5)
+	lim.AllowN(t10, 3)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lim.Count(t12)
+	}
+}
+
+func BenchmarkLimiter_Count(b *testing.B) {
+	benchmarkLimiterCount(b)
+}
+
+func BenchmarkLimiter_Count_WithCountCache(b *testing.B) {
+	benchmarkLimiterCount(b, WithCountCache(time.Second))
+}
@@ -0,0 +1,38 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// CurvePoint is a point on the decay curve.
+type CurvePoint struct {
+	Time  time.Time
+	Count int64
+}
+
+// DecayCurve returns how the count will decay from time now until the end
+// of the current window, as if no more events happen, which is useful for
+// visualization. The curve consists of steps+1 evenly spaced points, the
+// first of which is at time now, and the last at the end boundary (where
+// the count equals the current window's count).
+//
+// A non-positive steps is treated as 1.
+func (lim *Limiter) DecayCurve(now time.Time, steps int) []CurvePoint {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if steps < 1 {
+		steps = 1
+	}
+
+	lim.advance(now)
+	end := lim.aligner.Next(lim.curr.Start())
+	span := end.Sub(now)
+
+	points := make([]CurvePoint, steps+1)
+	for i := range points {
+		t := now.Add(span * time.Duration(i) / time.Duration(steps))
+		points[i] = CurvePoint{Time: t, Count: lim.countAt(t)}
+	}
+	return points
+}
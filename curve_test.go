@@ -0,0 +1,31 @@
+package slidingwindow
+
+import (
+	"testing"
+)
+
+func TestLimiter_DecayCurve(t *testing.T) {
+	lim, _ := NewLimiter(size, 100, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+	lim.AllowN(t5, 40)
+	lim.AllowN(t12, 10)
+
+	const steps = 8
+	curve := lim.DecayCurve(t12, steps)
+	if len(curve) != steps+1 {
+		t.Fatalf("len(curve) = %d, want: %d", len(curve), steps+1)
+	}
+
+	if p := curve[0]; !p.Time.Equal(t12) || p.Count != lim.Count(t12) {
+		t.Errorf("curve[0] = %v, want: {%v %d}", p, t12, lim.Count(t12))
+	}
+	for i := 1; i < len(curve); i++ {
+		if curve[i].Count > curve[i-1].Count {
+			t.Errorf("curve[%d].Count = %d, want: <= %d", i, curve[i].Count, curve[i-1].Count)
+		}
+	}
+	if p := curve[steps]; !p.Time.Equal(t0.Add(2*size)) || p.Count != 10 {
+		t.Errorf("curve[%d] = %v, want: {%v 10}", steps, p, t0.Add(2*size))
+	}
+}
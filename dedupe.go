@@ -0,0 +1,80 @@
+package slidingwindow
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// AllowNOnce is like AllowN, but deduplicates retried events by the
+// idempotency key: if the events of the same key have been admitted within
+// about one window size, they are not counted again and true is returned.
+// The denied events are never counted, thus their retries are decided anew.
+//
+// The keys are only tracked once AllowNOnce is used, and each of them is
+// kept for one window size after it is first admitted, so the memory grows
+// with the number of distinct keys per window.
+func (lim *Limiter) AllowNOnce(now time.Time, n int64, key string) bool {
+	d := lim.getDedupe()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.expire(now)
+	if d.seen(key) {
+		return true
+	}
+
+	ok := lim.AllowN(now, n)
+	if ok {
+		d.add(key, now.Add(lim.size))
+	}
+	return ok
+}
+
+func (lim *Limiter) getDedupe() *dedupe {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if lim.dedupe == nil {
+		lim.dedupe = &dedupe{
+			keys:  make(map[string]*list.Element),
+			order: list.New(),
+		}
+	}
+	return lim.dedupe
+}
+
+// dedupe is a set of recently-admitted keys, each of which expires after
+// a TTL.
+type dedupe struct {
+	mu    sync.Mutex
+	keys  map[string]*list.Element
+	order *list.List // From the earliest to the latest expiry.
+}
+
+type dedupeEntry struct {
+	key    string
+	expiry time.Time
+}
+
+func (d *dedupe) seen(key string) bool {
+	_, ok := d.keys[key]
+	return ok
+}
+
+func (d *dedupe) add(key string, expiry time.Time) {
+	d.keys[key] = d.order.PushBack(&dedupeEntry{key: key, expiry: expiry})
+}
+
+// expire removes all the keys that have expired at time now.
+func (d *dedupe) expire(now time.Time) {
+	for elem := d.order.Front(); elem != nil; elem = d.order.Front() {
+		entry := elem.Value.(*dedupeEntry)
+		if now.Before(entry.expiry) {
+			return
+		}
+		d.order.Remove(elem)
+		delete(d.keys, entry.key)
+	}
+}
@@ -0,0 +1,38 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_AllowNOnce(t *testing.T) {
+	lim, _ := NewLimiter(size, 10, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	steps := []struct {
+		at        int64 // In milliseconds since t0.
+		n         int64
+		key       string
+		wantOK    bool
+		wantCount int64
+	}{
+		{0, 3, "a", true, 3},
+		{100, 3, "a", true, 3}, // duplicate
+		{200, 3, "b", true, 6},
+		{300, 5, "c", false, 6},
+		{400, 1, "c", true, 7},  // denied before, i.e. decided anew
+		{500, 1, "c", true, 7},  // duplicate
+		{900, 3, "a", true, 7},  // still a duplicate
+		{1000, 2, "a", true, 9}, // expired, i.e. counted again
+	}
+	for _, s := range steps {
+		now := t0.Add(time.Duration(s.at) * time.Millisecond)
+		if ok := lim.AllowNOnce(now, s.n, s.key); ok != s.wantOK {
+			t.Errorf("at %dms: lim.AllowNOnce(%d, %q) = %v, want: %v", s.at, s.n, s.key, ok, s.wantOK)
+		}
+		if count := lim.Count(now); count != s.wantCount {
+			t.Errorf("at %dms: lim.Count() = %d, want: %d", s.at, count, s.wantCount)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// WithDemand makes the limiter additionally count all the attempts made by
+// AllowN, whether or not they are admitted, which can be queried by Demand.
+// This is useful for capacity planning, since the difference between the
+// demand and the count indicates how much traffic is shed.
+func WithDemand() Option {
+	return func(lim *Limiter) {
+		lim.demand = new(demand)
+	}
+}
+
+// Demand returns the approximate count of all the attempts made during the
+// sliding window ending at time now. It returns zero if the limiter is not
+// created with WithDemand.
+func (lim *Limiter) Demand(now time.Time) int64 {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if lim.demand == nil {
+		return 0
+	}
+
+	lim.advance(now)
+
+	start := lim.curr.Start()
+	end := lim.aligner.Next(start)
+	return lim.weighted(lim.demand.prev.Count(), lim.demand.curr.Count(), start, end, now)
+}
+
+// demand is a shadow pair of windows, which always moves along with
+// the limiter's windows.
+//
+// All methods are safe to call on a nil demand, which does nothing.
+type demand struct {
+	curr LocalWindow
+	prev LocalWindow
+}
+
+// Add records n attempted events.
+func (d *demand) Add(n int64) {
+	if d == nil {
+		return
+	}
+	d.curr.AddCount(n)
+}
+
+// Advance moves the windows to the new boundaries. If adjacent is true,
+// the previous window inherits the count of the current window.
+func (d *demand) Advance(prevStart, currStart time.Time, adjacent bool) {
+	if d == nil {
+		return
+	}
+
+	prevCount := int64(0)
+	if adjacent {
+		prevCount = d.curr.Count()
+	}
+	d.prev.Reset(prevStart, prevCount)
+	d.curr.Reset(currStart, 0)
+}
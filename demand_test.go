@@ -0,0 +1,52 @@
+package slidingwindow
+
+import (
+	"testing"
+)
+
+func TestLimiter_WithDemand(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithDemand())
+
+	cases := []caseArg{
+		{t1, 8, true},
+		{t2, 3, false},
+		{t3, 5, false},
+		{t4, 2, true},
+	}
+	for _, c := range cases {
+		if ok := lim.AllowN(c.t, c.n); ok != c.ok {
+			t.Errorf("lim.AllowN(%v, %v) = %v, want: %v", c.t, c.n, ok, c.ok)
+		}
+	}
+
+	if got := lim.Count(t5); got != 10 {
+		t.Errorf("lim.Count(%v) = %d, want: 10", t5, got)
+	}
+	if got := lim.Demand(t5); got != 18 {
+		t.Errorf("lim.Demand(%v) = %d, want: 18", t5, got)
+	}
+
+	// The demand decays just like the count.
+	if got := lim.Count(t15); got != 5 {
+		t.Errorf("lim.Count(%v) = %d, want: 5", t15, got)
+	}
+	if got := lim.Demand(t15); got != 9 {
+		t.Errorf("lim.Demand(%v) = %d, want: 9", t15, got)
+	}
+	if got := lim.Demand(t30); got != 0 {
+		t.Errorf("lim.Demand(%v) = %d, want: 0", t30, got)
+	}
+}
+
+func TestLimiter_Demand_Disabled(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+	lim.AllowN(t1, 3)
+
+	if got := lim.Demand(t1); got != 0 {
+		t.Errorf("lim.Demand(%v) = %d, want: 0", t1, got)
+	}
+}
@@ -0,0 +1,55 @@
+package slidingwindow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// countTolerance is the maximum difference between two weighted counts
+// that are still considered equal, which absorbs the rounding of the
+// weighted previous-window count.
+const countTolerance = 1
+
+// limiterState is a snapshot of a limiter for comparison.
+type limiterState struct {
+	count      int64
+	start, end time.Time
+}
+
+func (lim *Limiter) state(now time.Time) limiterState {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+	start := lim.curr.Start()
+	return limiterState{
+		count: lim.count(now),
+		start: start,
+		end:   lim.aligner.Next(start),
+	}
+}
+
+// EqualLimiters reports whether limiters a and b have the same weighted
+// count (within a tolerance of 1) and the same current-window boundaries
+// at time now. It is mainly intended for tests.
+func EqualLimiters(a, b *Limiter, now time.Time) bool {
+	return DiffLimiters(a, b, now) == ""
+}
+
+// DiffLimiters returns a human-readable description of the differences
+// between limiters a and b at time now, as compared by EqualLimiters, or an
+// empty string if there is none.
+func DiffLimiters(a, b *Limiter, now time.Time) string {
+	sa, sb := a.state(now), b.state(now)
+
+	var diffs []string
+	if d := sa.count - sb.count; d > countTolerance || d < -countTolerance {
+		diffs = append(diffs, fmt.Sprintf("count: %d != %d", sa.count, sb.count))
+	}
+	if !sa.start.Equal(sb.start) || !sa.end.Equal(sb.end) {
+		diffs = append(diffs, fmt.Sprintf("window: [%v, %v) != [%v, %v)",
+			sa.start, sa.end, sb.start, sb.end))
+	}
+	return strings.Join(diffs, "; ")
+}
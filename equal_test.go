@@ -0,0 +1,74 @@
+package slidingwindow
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEqualLimiters(t *testing.T) {
+	newLimiter := func(size time.Duration) *Limiter {
+		lim, _ := NewLimiter(size, 10, func() (Window, StopFunc) {
+			return NewLocalWindow()
+		})
+		return lim
+	}
+
+	cases := []struct {
+		name     string
+		setup    func(a, b *Limiter)
+		sizeB    time.Duration
+		wantDiff []string
+	}{
+		{
+			name:  "empty",
+			setup: func(a, b *Limiter) {},
+		},
+		{
+			name: "same counts",
+			setup: func(a, b *Limiter) {
+				a.AllowN(t0, 3)
+				b.AllowN(t0.Add(size/2), 3)
+			},
+		},
+		{
+			name: "different counts",
+			setup: func(a, b *Limiter) {
+				a.AllowN(t0, 3)
+				b.AllowN(t0, 5)
+			},
+			wantDiff: []string{"count: 3 != 5"},
+		},
+		{
+			name: "different windows",
+			setup: func(a, b *Limiter) {
+				a.AllowN(t0, 3)
+				b.AllowN(t0, 3)
+			},
+			sizeB:    2 * size,
+			wantDiff: []string{"window: "},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sizeB := size
+			if c.sizeB != 0 {
+				sizeB = c.sizeB
+			}
+			a, b := newLimiter(size), newLimiter(sizeB)
+			c.setup(a, b)
+
+			now := t0.Add(size / 4)
+			diff := DiffLimiters(a, b, now)
+			if got, want := EqualLimiters(a, b, now), len(c.wantDiff) == 0; got != want {
+				t.Errorf("EqualLimiters() = %v, want: %v (diff: %q)", got, want, diff)
+			}
+			for _, want := range c.wantDiff {
+				if !strings.Contains(diff, want) {
+					t.Errorf("DiffLimiters() = %q, want: containing %q", diff, want)
+				}
+			}
+		})
+	}
+}
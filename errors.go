@@ -0,0 +1,41 @@
+package slidingwindow
+
+import (
+	"errors"
+)
+
+// Errors returned by the package. They may be wrapped with additional
+// context, so always use errors.Is to check for them.
+var (
+	// ErrInvalidSize indicates that the window size is not positive.
+	ErrInvalidSize = errors.New("slidingwindow: invalid window size")
+
+	// ErrInvalidLimit indicates that the limit is negative.
+	ErrInvalidLimit = errors.New("slidingwindow: invalid limit")
+
+	// ErrInvalidInterval indicates that the sync interval is negative, or
+	// the batch interval of BatchDatastore is not positive.
+	ErrInvalidInterval = errors.New("slidingwindow: invalid sync interval")
+
+	// ErrInvalidWeight indicates that the weight function returns a weight
+	// out of [0, 1].
+	ErrInvalidWeight = errors.New("slidingwindow: invalid weight")
+
+	// ErrInvalidFactor indicates that the multiplicative decrease factor of
+	// AdaptiveLimiter is not within (0, 1).
+	ErrInvalidFactor = errors.New("slidingwindow: invalid decrease factor")
+
+	// ErrLimitExceeded indicates that the events are not permitted to happen
+	// since the limit has been exceeded.
+	ErrLimitExceeded = errors.New("slidingwindow: limit exceeded")
+
+	// ErrClosed indicates that the limiter has been stopped.
+	ErrClosed = errors.New("slidingwindow: limiter closed")
+
+	// ErrNotSupported indicates that the operation is not supported by
+	// the window or the datastore.
+	ErrNotSupported = errors.New("slidingwindow: not supported")
+
+	// ErrInvalidState indicates that the exported state is malformed.
+	ErrInvalidState = errors.New("slidingwindow: invalid state")
+)
@@ -0,0 +1,51 @@
+package slidingwindow
+
+import (
+	"math/bits"
+	"time"
+)
+
+// WithExactCount makes the limiter weight the count of the previous window
+// with rational arithmetic, instead of the float weight, whose rounding
+// errors may make the count off by one, e.g. for metering requiring
+// exactness.
+//
+// It only applies to the default linear weight, and is ignored if
+// WithWeightFunc is also specified.
+func WithExactCount() Option {
+	return func(lim *Limiter) {
+		lim.exact = true
+	}
+}
+
+// exactWeighted returns the weighted count of the previous window for the
+// sliding window ending at time t, where the current window is [start, end),
+// rounded down or up. It returns false if the exact count is not applicable.
+func (lim *Limiter) exactWeighted(prevCount int64, start, end, t time.Time, roundUp bool) (int64, bool) {
+	if !lim.exact || lim.weightFunc != nil || prevCount < 0 {
+		return 0, false
+	}
+	if lim.paused {
+		// No decay happens during the pause.
+		t = lim.pausedAt
+	}
+
+	den := end.Sub(start)
+	num := end.Sub(t)
+	if den <= 0 {
+		return 0, false
+	}
+	if num < 0 {
+		num = 0
+	} else if num > den {
+		num = den
+	}
+
+	// Since num <= den, the quotient always fits in 64 bits.
+	hi, lo := bits.Mul64(uint64(prevCount), uint64(num))
+	q, r := bits.Div64(hi, lo, uint64(den))
+	if roundUp && r > 0 {
+		q++
+	}
+	return int64(q), true
+}
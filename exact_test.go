@@ -0,0 +1,52 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_WithExactCount(t *testing.T) {
+	// At 420ms into the current window, the weight is exactly 0.58, and
+	// 50 * 0.58 = 29, while the float weight gives 28.999... instead.
+	now := t0.Add(size + 420*time.Millisecond)
+
+	cases := []struct {
+		name   string
+		opts   []Option
+		want   int64
+		wantUp int64
+	}{
+		{"float", nil, 28, 29}, // off by one
+		{"exact", []Option{WithExactCount()}, 29, 29},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lim, _ := NewLimiter(size, 100, newLocalWindow, c.opts...)
+			lim.AllowN(t0, 50)
+
+			if got := lim.Count(now); got != c.want {
+				t.Errorf("lim.Count(%v) = %d, want: %d", now, got, c.want)
+			}
+			if got := lim.CountUpperBound(now); got != c.wantUp {
+				t.Errorf("lim.CountUpperBound(%v) = %d, want: %d", now, got, c.wantUp)
+			}
+		})
+	}
+}
+
+func TestLimiter_WithExactCount_roundUp(t *testing.T) {
+	lim, _ := NewLimiter(size, 100, newLocalWindow, WithExactCount())
+	lim.AllowN(t0, 7)
+
+	// 7 * 0.5 = 3.5
+	now := t0.Add(size + size/2)
+	if got := lim.Count(now); got != 3 {
+		t.Errorf("lim.Count(%v) = %d, want: 3", now, got)
+	}
+	if got := lim.CountUpperBound(now); got != 4 {
+		t.Errorf("lim.CountUpperBound(%v) = %d, want: 4", now, got)
+	}
+	if got := lim.Explain(now).WeightedPrev; got != 3 {
+		t.Errorf("lim.Explain(%v).WeightedPrev = %d, want: 3", now, got)
+	}
+}
@@ -0,0 +1,24 @@
+package slidingwindow_test
+
+import (
+	"fmt"
+	"time"
+
+	sw "github.com/RussellLuo/slidingwindow"
+)
+
+func Example_calendarAligner() {
+	// Permit 1000 events per calendar month.
+	lim, _ := sw.NewLimiter(30*24*time.Hour, 1000, func() (sw.Window, sw.StopFunc) {
+		return sw.NewLocalWindow()
+	}, sw.WithAligner(sw.CalendarAligner{Months: 1}))
+
+	lim.AllowN(time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC), 600)
+
+	// Halfway through February (28 days), half of January's count remains.
+	count := lim.Count(time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC))
+	fmt.Printf("count: %d\n", count)
+
+	// Output:
+	// count: 300
+}
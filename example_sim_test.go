@@ -0,0 +1,31 @@
+package slidingwindow_test
+
+import (
+	"fmt"
+	"time"
+
+	sw "github.com/RussellLuo/slidingwindow"
+)
+
+func ExampleSimulate() {
+	lim, _ := sw.NewLimiter(time.Second, 10, func() (sw.Window, sw.StopFunc) {
+		return sw.NewLocalWindow()
+	})
+	clock := sw.NewSimClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	err := sw.Simulate(lim, clock, []sw.SimStep{
+		// A burst uses up the limit at once.
+		{At: 0, N: 10, Allow: true},
+		{At: 100 * time.Millisecond, N: 1, Allow: false},
+		// Right after the window rolls over, the burst still counts fully.
+		{At: 1000 * time.Millisecond, N: 1, Allow: false},
+		// Then its weight decays as the sliding window moves on.
+		{At: 1500 * time.Millisecond, N: 5, Allow: true},
+		{At: 1600 * time.Millisecond, N: 2, Allow: false},
+		{At: 1900 * time.Millisecond, N: 3, Allow: true},
+	})
+	fmt.Printf("err: %v\n", err)
+
+	// Output:
+	// err: <nil>
+}
@@ -0,0 +1,40 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// CountExplanation is the breakdown of the count.
+type CountExplanation struct {
+	PrevCount    int64   // The raw count of the previous window.
+	Weight       float64 // The weight of the previous window.
+	WeightedPrev int64   // PrevCount * Weight, rounded down.
+	CurrCount    int64   // The raw count of the current window.
+	Total        int64   // WeightedPrev + CurrCount.
+
+	WindowStart time.Time     // The start boundary of the current window.
+	Elapsed     time.Duration // The time elapsed within the current window.
+}
+
+// Explain advances the limiter to time now, and then returns how the count
+// considered by the decisions (i.e. including the outstanding reservations)
+// is calculated, which makes a questioned decision transparent.
+func (lim *Limiter) Explain(now time.Time) CountExplanation {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+
+	start := lim.curr.Start()
+	end := lim.aligner.Next(start)
+	e := CountExplanation{
+		PrevCount:   lim.prev.Count(),
+		Weight:      lim.weight(start, end, now),
+		CurrCount:   lim.curr.Count(),
+		WindowStart: start,
+		Elapsed:     now.Sub(start),
+	}
+	e.WeightedPrev = lim.weighted(e.PrevCount, 0, start, end, now)
+	e.Total = e.WeightedPrev + e.CurrCount
+	return e
+}
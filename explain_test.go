@@ -0,0 +1,37 @@
+package slidingwindow
+
+import (
+	"testing"
+)
+
+func TestLimiter_Explain(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, newLocalWindow)
+	lim.AllowN(t5, 5)
+	lim.AllowN(t13, 2)
+
+	want := CountExplanation{
+		PrevCount:    5,
+		Weight:       0.7,
+		WeightedPrev: 3,
+		CurrCount:    2,
+		Total:        5,
+		WindowStart:  t10,
+		Elapsed:      3 * d,
+	}
+	got := lim.Explain(t13)
+	if got.PrevCount != want.PrevCount || got.WeightedPrev != want.WeightedPrev ||
+		got.CurrCount != want.CurrCount || got.Total != want.Total ||
+		!got.WindowStart.Equal(want.WindowStart) || got.Elapsed != want.Elapsed {
+		t.Errorf("lim.Explain(%v) = %+v, want: %+v", t13, got, want)
+	}
+	if d := got.Weight - want.Weight; d > 1e-9 || d < -1e-9 {
+		t.Errorf("lim.Explain(%v).Weight = %v, want: %v", t13, got.Weight, want.Weight)
+	}
+
+	if got.WeightedPrev+got.CurrCount != got.Total {
+		t.Errorf("WeightedPrev + CurrCount = %d, want: %d", got.WeightedPrev+got.CurrCount, got.Total)
+	}
+	if count := lim.Count(t13); got.Total != count {
+		t.Errorf("lim.Explain(%v).Total = %d, want: %d", t13, got.Total, count)
+	}
+}
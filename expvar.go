@@ -0,0 +1,29 @@
+package slidingwindow
+
+import (
+	"expvar"
+	"time"
+)
+
+// PublishExpvar publishes the state of lim as an expvar variable with the
+// given name, so that it can be observed on /debug/vars. The variable is
+// evaluated on each read, and reports the current approximate count along
+// with the limit:
+//
+//	{"count": 12, "limit": 100}
+//
+// PublishExpvar is safe for concurrent use, but like expvar.Publish, it
+// panics if the name is already registered.
+func PublishExpvar(name string, lim *Limiter) {
+	publishExpvar(name, lim, time.Now)
+}
+
+// publishExpvar is PublishExpvar with the clock replaced by now.
+func publishExpvar(name string, lim *Limiter, now func() time.Time) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return map[string]int64{
+			"count": lim.Count(now()),
+			"limit": lim.Limit(),
+		}
+	}))
+}
@@ -0,0 +1,74 @@
+package slidingwindow
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// expvarSeq makes the published names unique across runs, e.g. with
+// -count, since expvar never unpublishes a name.
+var expvarSeq int64
+
+func expvarName(t *testing.T) string {
+	return fmt.Sprintf("%s_%d", t.Name(), atomic.AddInt64(&expvarSeq, 1))
+}
+
+func getExpvar(t *testing.T, name string) map[string]int64 {
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatalf("expvar.Get(%q) = nil", name)
+	}
+
+	var m map[string]int64
+	if err := json.Unmarshal([]byte(v.String()), &m); err != nil {
+		t.Fatalf("json.Unmarshal(%q) err: %v", v.String(), err)
+	}
+	return m
+}
+
+func TestPublishExpvar(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+	name := expvarName(t)
+	publishExpvar(name, lim, func() time.Time { return t1 })
+
+	got := getExpvar(t, name)
+	if got["count"] != 0 || got["limit"] != limit {
+		t.Errorf("expvar %s = %v, want: count=0, limit=%d", name, got, limit)
+	}
+
+	lim.AllowN(t1, 1)
+	lim.SetLimit(20)
+
+	got = getExpvar(t, name)
+	if got["count"] != 1 || got["limit"] != 20 {
+		t.Errorf("expvar %s = %v, want: count=1, limit=20", name, got)
+	}
+}
+
+func TestPublishExpvar_Concurrent(t *testing.T) {
+	prefix := expvarName(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+				return NewLocalWindow()
+			})
+			PublishExpvar(fmt.Sprintf("%s_%d", prefix, i), lim)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 100; i++ {
+		getExpvar(t, fmt.Sprintf("%s_%d", prefix, i))
+	}
+}
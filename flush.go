@@ -0,0 +1,46 @@
+package slidingwindow
+
+import (
+	"context"
+)
+
+// flusher is implemented by windows which can sync on demand.
+type flusher interface {
+	// flush syncs the window, where the state of the window is only
+	// accessed within locked, which runs f with the window's lock held.
+	flush(ctx context.Context, locked func(f func())) error
+}
+
+// Flush forces the current window to immediately sync its changes to the
+// central datastore, which is useful right before making a decision that
+// must be consistent across nodes. It returns the error from the datastore,
+// if any, or the context's error once the context is done. For a window
+// without sync behaviour (e.g. LocalWindow), Flush is a no-op.
+//
+// The lock of the limiter is not held during the exchange with the central
+// datastore, thus the other calls are never stalled by Flush.
+func (lim *Limiter) Flush(ctx context.Context) error {
+	lim.mu.Lock()
+	if lim.closed {
+		lim.mu.Unlock()
+		return ErrClosed
+	}
+	// The current window is always the same one, which is only reset
+	// to move along.
+	f, ok := lim.curr.(flusher)
+	lim.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return f.flush(ctx, lim.locked)
+}
+
+// locked runs f with the lock held, as a change to the windows.
+func (lim *Limiter) locked(f func()) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	f()
+	lim.gen++
+}
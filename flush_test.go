@@ -0,0 +1,198 @@
+package slidingwindow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type errDatastore struct {
+	err error
+}
+
+func (d errDatastore) Add(key string, start, delta int64) (int64, error) {
+	return 0, d.err
+}
+
+func (d errDatastore) Get(key string, start int64) (int64, error) {
+	return 0, d.err
+}
+
+func TestLimiter_Flush(t *testing.T) {
+	newSyncers := map[string]func(Datastore) Synchronizer{
+		"blocking": func(store Datastore) Synchronizer {
+			return NewBlockingSynchronizer(store, time.Hour)
+		},
+		"nonblocking": func(store Datastore) Synchronizer {
+			return NewNonblockingSynchronizer(store, time.Hour)
+		},
+	}
+	for name, newSyncer := range newSyncers {
+		newSyncer := newSyncer
+		t.Run(name, func(t *testing.T) {
+			store := newMemDatastore()
+			lim, stop := NewLimiter(size, limit, func() (Window, StopFunc) {
+				return NewSyncWindow("test", newSyncer(store))
+			})
+			defer stop()
+
+			// The first sync happens right after the first call, the later
+			// changes are pending due to the long sync interval.
+			lim.AllowN(t1, 3)
+			lim.AllowN(t2, 2)
+
+			if err := lim.Flush(context.Background()); err != nil {
+				t.Fatalf("lim.Flush() err: %v", err)
+			}
+			if got, _ := store.Get("test", t0.UnixNano()); got != 5 {
+				t.Errorf("store.Get() = %d, want: 5", got)
+			}
+
+			// Nothing is pending now.
+			if err := lim.Flush(context.Background()); err != nil {
+				t.Fatalf("lim.Flush() err: %v", err)
+			}
+			if got, _ := store.Get("test", t0.UnixNano()); got != 5 {
+				t.Errorf("store.Get() = %d, want: 5", got)
+			}
+		})
+	}
+}
+
+func TestLimiter_Flush_Error(t *testing.T) {
+	errStore := errors.New("store is down")
+	lim, stop := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(errDatastore{errStore}, time.Hour))
+	})
+
+	lim.AllowN(t1, 3)
+	if err := lim.Flush(context.Background()); err != errStore {
+		t.Errorf("lim.Flush() err: %v, want: %v", err, errStore)
+	}
+
+	stop()
+	if err := lim.Flush(context.Background()); err != ErrClosed {
+		t.Errorf("lim.Flush() err: %v, want: %v", err, ErrClosed)
+	}
+}
+
+func TestLimiter_Flush_LocalWindow(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+	if err := lim.Flush(context.Background()); err != nil {
+		t.Errorf("lim.Flush() err: %v, want: nil", err)
+	}
+}
+
+func TestLimiter_StopWithResult(t *testing.T) {
+	errStore := errors.New("store is down")
+	lim, stop := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(errDatastore{errStore}, time.Hour))
+	})
+	lim.AllowN(t0, 1)
+
+	if err := lim.StopWithResult(); err != errStore {
+		t.Errorf("lim.StopWithResult() err: %v, want: %v", err, errStore)
+	}
+	// Idempotent, also for the stop function.
+	if err := lim.StopWithResult(); err != nil {
+		t.Errorf("lim.StopWithResult() err: %v, want: nil", err)
+	}
+	stop()
+
+	// The stop function does a final flush as well.
+	store := newMemDatastore()
+	lim, stop = NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(store, time.Hour))
+	})
+	lim.AllowN(t0, 1) // synced at once
+	lim.AllowN(t1, 2)
+	stop()
+	if got, _ := store.Get("test", t0.UnixNano()); got != 3 {
+		t.Errorf("the count in the datastore = %d, want: 3", got)
+	}
+}
+
+func TestLimiter_Flush_Unlocked(t *testing.T) {
+	newSyncers := map[string]func(Datastore) Synchronizer{
+		"blocking": func(store Datastore) Synchronizer {
+			return NewBlockingSynchronizer(store, time.Hour)
+		},
+		"nonblocking": func(store Datastore) Synchronizer {
+			return NewNonblockingSynchronizer(store, time.Hour)
+		},
+	}
+	for name, newSyncer := range newSyncers {
+		newSyncer := newSyncer
+		t.Run(name, func(t *testing.T) {
+			store := &slowDatastore{MemDatastore: newMemDatastore(), release: make(chan struct{})}
+			lim, stop := NewLimiter(size, limit, func() (Window, StopFunc) {
+				return NewSyncWindow("test", newSyncer(store))
+			})
+			defer stop()
+
+			// The first sync only gets the count, which is never slow.
+			lim.AllowN(t1, 0)
+			lim.AllowN(t2, 3)
+
+			errC := make(chan error, 1)
+			go func() { errC <- lim.Flush(context.Background()) }()
+
+			// The other calls go on while the flush is stuck.
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				lim.AllowN(t2, 1)
+				lim.Count(t2)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("lim.AllowN() is stalled by lim.Flush()")
+			}
+
+			close(store.release)
+			if err := <-errC; err != nil {
+				t.Fatalf("lim.Flush() err: %v", err)
+			}
+			if err := lim.Flush(context.Background()); err != nil {
+				t.Fatalf("lim.Flush() err: %v", err)
+			}
+			if got, _ := store.Get("test", t0.UnixNano()); got != 4 {
+				t.Errorf("store.Get() = %d, want: 4", got)
+			}
+		})
+	}
+}
+
+func TestLimiter_Flush_Context(t *testing.T) {
+	store := &slowDatastore{MemDatastore: newMemDatastore(), release: make(chan struct{})}
+	lim, stop := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(store, time.Hour))
+	})
+	defer stop()
+
+	lim.AllowN(t1, 0)
+	lim.AllowN(t2, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := lim.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("lim.Flush() err: %v, want: %v", err, context.DeadlineExceeded)
+	}
+
+	// The response of the abandoned flush is still handled, thus the
+	// changes are never sent twice.
+	close(store.release)
+	if err := lim.Flush(context.Background()); err != nil {
+		t.Fatalf("lim.Flush() err: %v", err)
+	}
+	if got, _ := store.Get("test", t0.UnixNano()); got != 3 {
+		t.Errorf("store.Get() = %d, want: 3", got)
+	}
+	if got := lim.Count(t2); got != 3 {
+		t.Errorf("lim.Count() = %d, want: 3", got)
+	}
+}
@@ -0,0 +1,39 @@
+package slidingwindow
+
+import (
+	"math"
+	"time"
+)
+
+// WithForwardLooking makes Count report a different estimator: instead of
+// blending the previous window's count with the current one, it projects
+// the current window's rate so far onto a full window, i.e. the count the
+// current window would end with at the upcoming boundary if the rate kept
+// unchanged.
+//
+// This reduces the lag in detecting a rising trend (and a falling one),
+// at the cost of being noisy early in a window, when few events have
+// been observed. Right at the start of a window, the standard count is
+// reported instead.
+//
+// Note that it only changes the count reported by Count, the decisions
+// are always made by the standard count.
+func WithForwardLooking() Option {
+	return func(lim *Limiter) {
+		lim.forwardLooking = true
+	}
+}
+
+// projectedCount projects currCount, observed within [start, now), onto
+// the full window [start, end). It returns false if nothing has elapsed.
+func projectedCount(currCount int64, start, end, now time.Time) (int64, bool) {
+	elapsed := now.Sub(start)
+	if elapsed <= 0 {
+		return 0, false
+	}
+	projected := float64(currCount) * float64(end.Sub(start)) / float64(elapsed)
+	if projected >= math.MaxInt64 {
+		return math.MaxInt64, true
+	}
+	return int64(projected), true
+}
@@ -0,0 +1,38 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_WithForwardLooking(t *testing.T) {
+	newLimiter := func(opts ...Option) *Limiter {
+		lim, _ := NewLimiter(size, 1000, func() (Window, StopFunc) {
+			return NewLocalWindow()
+		}, opts...)
+		return lim
+	}
+	standard, forward := newLimiter(), newLimiter(WithForwardLooking())
+
+	// A rising ramp, where i events happen at the i-th interval.
+	for i := int64(1); i <= 15; i++ {
+		now := t0.Add(time.Duration(i) * d)
+		standard.AllowN(now, i)
+		forward.AllowN(now, i)
+	}
+
+	// Standard: (1 + ... + 9) * 1/2 + (10 + ... + 15) = 22 + 75 = 97.
+	// Forward: (10 + ... + 15) * 10/5 = 150.
+	now := t15
+	if got := standard.Count(now); got != 97 {
+		t.Errorf("standard.Count(%v) = %d, want: 97", now, got)
+	}
+	if got := forward.Count(now); got != 150 {
+		t.Errorf("forward.Count(%v) = %d, want: 150", now, got)
+	}
+
+	// Right at the start of a window, the standard count is reported.
+	if got, want := forward.Count(t0.Add(2*size)), standard.Count(t0.Add(2*size)); got != want {
+		t.Errorf("forward.Count(%v) = %d, want: %d", t0.Add(2*size), got, want)
+	}
+}
@@ -0,0 +1,35 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// GapHook is called with the number of the complete windows within
+// [from, to), which have been skipped with zero activity.
+type GapHook func(skipped int, from, to time.Time)
+
+// WithGapHook sets the hook which is called whenever the limiter is used
+// after being idle for one or more complete windows, which helps to
+// reconcile the usage records expecting a value per window.
+//
+// Unlike the deny hook, the gap hook is called synchronously with the
+// limiter's lock held, thus it must not call the limiter's methods.
+func WithGapHook(hook GapHook) Option {
+	return func(lim *Limiter) {
+		lim.gapHook = hook
+	}
+}
+
+// countWindows returns the number of the windows within [from, to), where
+// from is a window boundary.
+func countWindows(a Aligner, from, to time.Time) int {
+	if sa, ok := a.(sizeAligner); ok {
+		return int(to.Sub(from) / sa.size)
+	}
+
+	n := 0
+	for t := from; t.Before(to); t = a.Next(t) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,48 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_WithGapHook(t *testing.T) {
+	type gap struct {
+		skipped  int
+		from, to time.Time
+	}
+
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "size aligner"},
+		// The quantum makes no difference here, but leads to counting
+		// the windows by iteration.
+		{name: "quantum aligner", opts: []Option{WithStartQuantum(size / 2)}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var gaps []gap
+			opts := append(c.opts, WithGapHook(func(skipped int, from, to time.Time) {
+				gaps = append(gaps, gap{skipped, from, to})
+			}))
+			lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+				return NewLocalWindow()
+			}, opts...)
+
+			lim.AllowN(t0, 1)
+			lim.AllowN(t15, 1) // adjacent, no gap
+			lim.AllowN(t0.Add(5*size), 1)
+
+			want := []gap{{3, t0.Add(2 * size), t0.Add(5 * size)}}
+			if len(gaps) != len(want) {
+				t.Fatalf("gaps = %v, want: %v", gaps, want)
+			}
+			for i := range want {
+				if g := gaps[i]; g.skipped != want[i].skipped || !g.from.Equal(want[i].from) || !g.to.Equal(want[i].to) {
+					t.Errorf("gaps[%d] = %v, want: %v", i, g, want[i])
+				}
+			}
+		})
+	}
+}
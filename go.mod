@@ -1,3 +1,8 @@
 module github.com/RussellLuo/slidingwindow
 
 go 1.13
+
+require (
+	github.com/go-redis/redis v6.15.9+incompatible
+	golang.org/x/time v0.3.0
+)
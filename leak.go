@@ -0,0 +1,48 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// Leak advances the limiter to time now, and then drains the count of the
+// current window in proportion to the time elapsed since the last call, at
+// the rate of limit per window size (i.e. a full window drains in one window
+// size). Calling Leak regularly (e.g. before each AllowN) makes the limiter
+// emulate a leaky bucket, where the count drains continuously rather than
+// stepping at the window boundaries.
+//
+// The first call only marks the time from which to leak. The fractional
+// amount is carried over to the next call, unless the window is drained.
+//
+// On a SyncWindow, the leaked count is synced to the central datastore,
+// thus the shared count drains for all the nodes. Since every call drains
+// at the full rate, only one of the nodes sharing the datastore should call
+// Leak, otherwise the shared count drains as many times faster.
+func (lim *Limiter) Leak(now time.Time) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+
+	if lim.lastLeak.IsZero() || !now.After(lim.lastLeak) {
+		if lim.lastLeak.IsZero() {
+			lim.lastLeak = now
+		}
+		return
+	}
+	elapsed := now.Sub(lim.lastLeak)
+	lim.lastLeak = now
+
+	amount := float64(lim.limit)*float64(elapsed)/float64(lim.size) + lim.leakRemainder
+	n := int64(amount)
+	lim.leakRemainder = amount - float64(n)
+
+	if count := lim.curr.Count(); n >= count {
+		// Nothing is left to leak, so no more credit is kept.
+		n, lim.leakRemainder = count, 0
+	}
+	if n > 0 {
+		lim.curr.AddCount(-n)
+		lim.gen++
+	}
+}
@@ -0,0 +1,87 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Leak(t *testing.T) {
+	lim, _ := NewLimiter(size, 100, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	lim.AllowN(t0, 100)
+	lim.Leak(t0)
+
+	// The count drains by 100 per window size, i.e. 1 per 10ms.
+	steps := []struct {
+		at        time.Duration
+		wantCount int64
+	}{
+		{5 * time.Millisecond, 100}, // the fractional amount is carried over
+		{10 * time.Millisecond, 99},
+		{d, 90},
+		{d + d/2, 85},
+		{3 * d, 70},
+		{9 * d, 10},
+		{9*d + 50*time.Millisecond, 5},
+	}
+	for _, s := range steps {
+		now := t0.Add(s.at)
+		lim.Leak(now)
+		if got := lim.Count(now); got != s.wantCount {
+			t.Errorf("lim.Count(%v) = %d, want: %d", now, got, s.wantCount)
+		}
+	}
+}
+
+func TestLimiter_Leak_syncWindow(t *testing.T) {
+	store := newMemDatastore()
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+	})
+
+	lim.AllowN(t0, 10)
+	lim.Leak(t0)
+	lim.Leak(t5)
+
+	// The leaked count is synced, instead of coming back at the next sync.
+	lim.AllowN(t6, 0)
+	if got := lim.Count(t6); got != 5 {
+		t.Errorf("lim.Count() = %d, want: 5", got)
+	}
+	if got, _ := store.Get("test", t0.UnixNano()); got != 5 {
+		t.Errorf("store.Get() = %d, want: 5", got)
+	}
+}
+
+func TestLimiter_Leak_multiNode(t *testing.T) {
+	store := newMemDatastore()
+	newSyncLimiter := func() *Limiter {
+		lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+			return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+		})
+		return lim
+	}
+	a, b := newSyncLimiter(), newSyncLimiter()
+
+	a.AllowN(t0, 6)
+	b.AllowN(t0, 4)
+	a.AllowN(t0, 0)
+
+	// Only a leaks, which drains the shared count at the rate of limit per
+	// window size for both nodes.
+	a.Leak(t0)
+	a.Leak(t5)
+	a.AllowN(t6, 0)
+	b.AllowN(t6, 0)
+	if got := a.Count(t6); got != 5 {
+		t.Errorf("a.Count(%v) = %d, want: 5", t6, got)
+	}
+	if got := b.Count(t6); got != 5 {
+		t.Errorf("b.Count(%v) = %d, want: 5", t6, got)
+	}
+	if got, _ := store.Get("test", t0.UnixNano()); got != 5 {
+		t.Errorf("store.Get() = %d, want: 5", got)
+	}
+}
@@ -0,0 +1,186 @@
+package slidingwindow
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// NewKeyedLimiter creates a new limiter for the given key, and returns
+// a function to stop the possible sync behaviour within it.
+type NewKeyedLimiter func(key string) (*Limiter, StopFunc)
+
+// MapOption configures optional behaviours of a limiter map.
+type MapOption func(*LimiterMap)
+
+// WithMaxKeys sets the maximum number of limiters held by the map. Once the
+// cap is exceeded, the least recently used limiter is evicted and stopped.
+// A non-positive n means no cap, which is the default.
+func WithMaxKeys(n int) MapOption {
+	return func(m *LimiterMap) {
+		m.maxKeys = n
+	}
+}
+
+// WithLimitFunc sets a function which decides the limit of each newly
+// created limiter by its key, e.g. to give premium users higher limits.
+// It overrides the limit set by the NewKeyedLimiter function.
+func WithLimitFunc(f func(key string) int64) MapOption {
+	return func(m *LimiterMap) {
+		m.limitFunc = f
+	}
+}
+
+// LimiterMap holds a set of limiters, one per key, which are created lazily
+// on first use.
+type LimiterMap struct {
+	newLimiter NewKeyedLimiter
+	maxKeys    int
+	limitFunc  func(key string) int64
+
+	mu sync.Mutex
+
+	// The most recently used entry is at the front.
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type limiterEntry struct {
+	key  string
+	lim  *Limiter
+	stop StopFunc
+}
+
+// NewLimiterMap creates a new limiter map, which creates its limiters
+// by newLimiter.
+func NewLimiterMap(newLimiter NewKeyedLimiter, opts ...MapOption) *LimiterMap {
+	m := &LimiterMap{
+		newLimiter: newLimiter,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Get returns the limiter for the given key, creating one if necessary.
+//
+// Note that a limiter may be evicted, and thus stopped, while it is still
+// in use by a caller. Such a limiter keeps working locally, but a new one
+// will be created for the same key by the next call to Get.
+func (m *LimiterMap) Get(key string) *Limiter {
+	m.mu.Lock()
+
+	if elem, ok := m.items[key]; ok {
+		m.ll.MoveToFront(elem)
+		m.mu.Unlock()
+		return elem.Value.(*limiterEntry).lim
+	}
+
+	lim, stop := m.newLimiter(key)
+	if m.limitFunc != nil {
+		lim.SetLimit(m.limitFunc(key))
+	}
+	m.items[key] = m.ll.PushFront(&limiterEntry{key: key, lim: lim, stop: stop})
+
+	var evicted []*limiterEntry
+	for m.maxKeys > 0 && m.ll.Len() > m.maxKeys {
+		evicted = append(evicted, m.remove(m.ll.Back()))
+	}
+	m.mu.Unlock()
+
+	// Stop evicted limiters outside the lock, since stopping may block
+	// until the sync goroutine exits.
+	for _, e := range evicted {
+		e.stop()
+	}
+	return lim
+}
+
+// SetLimit sets a new limit for the limiter of the given key, creating
+// the limiter if necessary.
+//
+// Note that the new limit is lost once the limiter is evicted or deleted.
+// To make a change (e.g. a tier upgrade) permanent, also reflect it in
+// the function set by WithLimitFunc.
+func (m *LimiterMap) SetLimit(key string, limit int64) {
+	m.Get(key).SetLimit(limit)
+}
+
+// Len returns the number of limiters held by the map.
+func (m *LimiterMap) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ll.Len()
+}
+
+// Delete removes and stops the limiter for the given key, if any.
+func (m *LimiterMap) Delete(key string) {
+	m.mu.Lock()
+	elem, ok := m.items[key]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	e := m.remove(elem)
+	m.mu.Unlock()
+
+	e.stop()
+}
+
+// Export removes and stops the limiter for the given key, and returns its
+// state at time now encoded by ExportState, which can be loaded into another
+// map by Import, e.g. to move the key to a new shard. It returns false if
+// there is no limiter for the key.
+//
+// The move is at-most-once: since the limiter is stopped (i.e. no more sync
+// behaviour) and removed before its state is exported, the counts are never
+// shared by two maps, but will be lost if the state fails to be imported.
+// Any call to Get for the key in the meantime creates a fresh limiter.
+func (m *LimiterMap) Export(key string, now time.Time) ([]byte, bool) {
+	m.mu.Lock()
+	elem, ok := m.items[key]
+	if !ok {
+		m.mu.Unlock()
+		return nil, false
+	}
+	e := m.remove(elem)
+	m.mu.Unlock()
+
+	e.stop()
+	return e.lim.ExportState(now), true
+}
+
+// Import loads the state exported by Export into the limiter for the given
+// key at time now, creating the limiter if necessary. The counts of any
+// existing limiter are replaced.
+//
+// If the limiters of both maps are backed by SyncWindows sharing the same
+// datastore, the counts already synced by the exported limiter are not
+// synced again (see ImportState), so the key is never counted twice.
+func (m *LimiterMap) Import(key string, data []byte, now time.Time) error {
+	return m.Get(key).ImportState(data, now)
+}
+
+// Stop removes and stops all the limiters held by the map.
+func (m *LimiterMap) Stop() {
+	m.mu.Lock()
+	var entries []*limiterEntry
+	for m.ll.Len() > 0 {
+		entries = append(entries, m.remove(m.ll.Back()))
+	}
+	m.mu.Unlock()
+
+	for _, e := range entries {
+		e.stop()
+	}
+}
+
+// remove removes elem from the map, and returns its entry.
+func (m *LimiterMap) remove(elem *list.Element) *limiterEntry {
+	e := m.ll.Remove(elem).(*limiterEntry)
+	delete(m.items, e.key)
+	return e
+}
@@ -0,0 +1,166 @@
+package slidingwindow
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLimiterMap_WithMaxKeys(t *testing.T) {
+	stopped := make(map[string]bool)
+	m := NewLimiterMap(func(key string) (*Limiter, StopFunc) {
+		lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+			return NewLocalWindow()
+		})
+		return lim, func() { stopped[key] = true }
+	}, WithMaxKeys(3))
+
+	for i := 0; i < 10; i++ {
+		m.Get(fmt.Sprintf("key%d", i))
+		if n := m.Len(); n > 3 {
+			t.Fatalf("m.Len() = %d, want: <= 3", n)
+		}
+	}
+
+	// Now the map holds key7, key8 and key9. Touch key7 so that key8
+	// becomes the least recently used one.
+	lim7 := m.Get("key7")
+	m.Get("key10")
+
+	if !stopped["key8"] {
+		t.Errorf("key8 is not evicted")
+	}
+	if stopped["key7"] {
+		t.Errorf("key7 is evicted")
+	}
+	if got := m.Get("key7"); got != lim7 {
+		t.Errorf("m.Get(key7) returns a new limiter")
+	}
+	for i := 0; i < 7; i++ {
+		if key := fmt.Sprintf("key%d", i); !stopped[key] {
+			t.Errorf("%s is not stopped", key)
+		}
+	}
+
+	m.Stop()
+	if n := m.Len(); n != 0 {
+		t.Errorf("m.Len() = %d, want: 0", n)
+	}
+	if !stopped["key10"] {
+		t.Errorf("key10 is not stopped")
+	}
+}
+
+func TestLimiterMap_WithLimitFunc(t *testing.T) {
+	m := NewLimiterMap(func(key string) (*Limiter, StopFunc) {
+		return NewLimiter(size, limit, func() (Window, StopFunc) {
+			return NewLocalWindow()
+		})
+	}, WithLimitFunc(func(key string) int64 {
+		if key == "premium" {
+			return 2 * limit
+		}
+		return limit
+	}))
+
+	if ok := m.Get("free").AllowN(t0, limit+1); ok {
+		t.Errorf("free: AllowN(%d) = true, want: false", limit+1)
+	}
+	if ok := m.Get("premium").AllowN(t0, limit+1); !ok {
+		t.Errorf("premium: AllowN(%d) = false, want: true", limit+1)
+	}
+
+	// Upgrade the tier of the free user.
+	m.SetLimit("free", 3*limit)
+	if got := m.Get("free").Limit(); got != 3*limit {
+		t.Errorf("free: Limit() = %d, want: %d", got, 3*limit)
+	}
+	if ok := m.Get("free").AllowN(t0, limit+1); !ok {
+		t.Errorf("free: AllowN(%d) = false, want: true", limit+1)
+	}
+}
+
+func TestLimiterMap_Export(t *testing.T) {
+	stopped := make(map[string]bool)
+	newMap := func() *LimiterMap {
+		return NewLimiterMap(func(key string) (*Limiter, StopFunc) {
+			lim, stop := NewLimiter(size, limit, newLocalWindow)
+			return lim, func() {
+				stopped[key] = true
+				stop()
+			}
+		})
+	}
+	src, dst := newMap(), newMap()
+
+	src.Get("key").AllowN(t5, 4)
+	src.Get("key").AllowN(t12, 3)
+
+	data, ok := src.Export("key", t12)
+	if !ok {
+		t.Fatalf("src.Export() = false, want: true")
+	}
+	if src.Len() != 0 || !stopped["key"] {
+		t.Errorf("the exported limiter is not removed and stopped")
+	}
+	if _, ok := src.Export("key", t12); ok {
+		t.Errorf("src.Export() of a moved key = true, want: false")
+	}
+
+	if err := dst.Import("key", data, t12); err != nil {
+		t.Fatalf("dst.Import() err: %v", err)
+	}
+	// 4 * 8/10 + 3 = 6.
+	if got := dst.Get("key").Count(t12); got != 6 {
+		t.Errorf("dst.Get().Count(%v) = %d, want: 6", t12, got)
+	}
+}
+
+func TestLimiterMap_Export_idle(t *testing.T) {
+	newMap := func() *LimiterMap {
+		return NewLimiterMap(func(key string) (*Limiter, StopFunc) {
+			return NewLimiter(size, limit, newLocalWindow)
+		})
+	}
+	src, dst := newMap(), newMap()
+
+	// The key has been idle for more than one window before the move.
+	src.Get("key").AllowN(t0, limit)
+	data, _ := src.Export("key", t30)
+
+	if err := dst.Import("key", data, t30); err != nil {
+		t.Fatalf("dst.Import() err: %v", err)
+	}
+	if got := dst.Get("key").Count(t30); got != 0 {
+		t.Errorf("dst.Get().Count(%v) = %d, want: 0", t30, got)
+	}
+	if !dst.Get("key").AllowN(t30, limit) {
+		t.Errorf("dst.Get().AllowN(%v, %d) = false, want: true", t30, limit)
+	}
+}
+
+func TestLimiterMap_Export_sharedDatastore(t *testing.T) {
+	store := newMemDatastore()
+	newMap := func() *LimiterMap {
+		return NewLimiterMap(func(key string) (*Limiter, StopFunc) {
+			return NewLimiter(size, limit, func() (Window, StopFunc) {
+				return NewSyncWindow(key, NewBlockingSynchronizer(store, 0))
+			})
+		})
+	}
+	src, dst := newMap(), newMap()
+
+	src.Get("key").AllowN(t1, 4)
+	data, _ := src.Export("key", t2)
+	if err := dst.Import("key", data, t2); err != nil {
+		t.Fatalf("dst.Import() err: %v", err)
+	}
+
+	// The key is not counted twice by the maps sharing the datastore.
+	dst.Get("key").AllowN(t3, 1)
+	if got := dst.Get("key").Count(t3); got != 5 {
+		t.Errorf("dst.Get().Count(%v) = %d, want: 5", t3, got)
+	}
+	if got, _ := store.Get("key", t0.UnixNano()); got != 5 {
+		t.Errorf("store.Get() = %d, want: 5", got)
+	}
+}
@@ -0,0 +1,47 @@
+package slidingwindow
+
+import (
+	"log"
+)
+
+// Logger is a minimal logging interface, through which internal anomalies
+// (e.g. sync failures, or the clock moving backwards) are reported.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+// WithLogger sets the logger of the limiter, which is also passed to the
+// synchronizer of a SyncWindow.
+//
+// By default, the limiter logs nothing, while the built-in synchronizers
+// report sync failures through the standard log package.
+func WithLogger(l Logger) Option {
+	return func(lim *Limiter) {
+		if l == nil {
+			l = nopLogger{}
+		}
+		lim.logger = l
+	}
+}
+
+// loggerSetter is implemented by windows and synchronizers which accept
+// a logger from the limiter.
+type loggerSetter interface {
+	setLogger(l Logger)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+
+func (nopLogger) Warnf(format string, args ...interface{}) {}
+
+// stdLogger logs warnings through the standard log package.
+type stdLogger struct{}
+
+func (stdLogger) Debugf(format string, args ...interface{}) {}
+
+func (stdLogger) Warnf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
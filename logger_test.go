@@ -0,0 +1,61 @@
+package slidingwindow
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordLogger struct {
+	mu    sync.Mutex
+	warns []string
+}
+
+func (l *recordLogger) Debugf(format string, args ...interface{}) {}
+
+func (l *recordLogger) Warnf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warns = append(l.warns, fmt.Sprintf(format, args...))
+}
+
+func (l *recordLogger) Warns() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.warns...)
+}
+
+func TestLimiter_WithLogger(t *testing.T) {
+	logger := new(recordLogger)
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		store := errDatastore{errors.New("store is down")}
+		return NewSyncWindow("test", NewBlockingSynchronizer(store, time.Hour))
+	}, WithLogger(logger))
+
+	lim.AllowN(t2, 1)                                                    // sync failure
+	lim.AllowN(t1, 1)                                                    // clock moved backwards
+	lim.AllowNWithOptions(t2, math.MaxInt64, AllowOptions{Exempt: true}) // overflow
+
+	want := []string{
+		"slidingwindow: sync failed: store is down",
+		"slidingwindow: clock moved backwards",
+		"slidingwindow: count saturated",
+	}
+	warns := logger.Warns()
+	if len(warns) != len(want) {
+		t.Fatalf("warns = %q, want: %q", warns, want)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(warns[i], w) {
+			t.Errorf("warns[%d] = %q, want prefix: %q", i, warns[i], w)
+		}
+	}
+
+	if got := lim.Count(t2); got != math.MaxInt64 {
+		t.Errorf("lim.Count(%v) = %d, want: %d", t2, got, int64(math.MaxInt64))
+	}
+}
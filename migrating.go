@@ -0,0 +1,104 @@
+package slidingwindow
+
+import (
+	"sync"
+	"time"
+)
+
+// MigratingLimiter migrates the events from an old limiter to a new one
+// (typically, of a different window size) gracefully, by running both of
+// them in parallel for a transition period.
+//
+// The new limiter only sees the events since the migration started, thus
+// its count is underestimated until it is warmed up, i.e. once its previous
+// window is a full one (which is up to two window sizes later, depending on
+// the alignment of the windows). During the transition, the decisions are
+// made by the old limiter, and the admitted events are also counted in the
+// new one. Since then, the decisions are made by the new limiter, and the
+// old one is stopped.
+type MigratingLimiter struct {
+	mu      sync.Mutex
+	old     *Limiter
+	stopOld StopFunc
+	new     *Limiter
+	stopNew StopFunc
+
+	warmAt time.Time // The time at which the new limiter is warmed up.
+}
+
+// NewMigratingLimiter starts to migrate from the old limiter to the new one
+// at time now.
+func NewMigratingLimiter(now time.Time, oldLim *Limiter, stopOld StopFunc, newLim *Limiter, stopNew StopFunc) *MigratingLimiter {
+	// The first full window of the new limiter starts at the next boundary,
+	// and becomes the previous window one window later.
+	a := newLim.aligner
+	warmAt := a.Next(a.Next(a.Align(now)))
+
+	return &MigratingLimiter{
+		old:     oldLim,
+		stopOld: stopOld,
+		new:     newLim,
+		stopNew: stopNew,
+		warmAt:  warmAt,
+	}
+}
+
+// serving returns the limiter making the decisions at time now, and the
+// one only fed with the admitted events (nil if the transition is over).
+func (m *MigratingLimiter) serving(now time.Time) (serving, fed *Limiter) {
+	m.mu.Lock()
+	if m.old == nil {
+		m.mu.Unlock()
+		return m.new, nil
+	}
+	if !now.Before(m.warmAt) {
+		// The transition is over. Stop the old limiter without the lock
+		// held, since the final flush may block.
+		m.old = nil
+		m.mu.Unlock()
+		m.stopOld()
+		return m.new, nil
+	}
+	serving, fed = m.old, m.new
+	m.mu.Unlock()
+	return serving, fed
+}
+
+// AllowN reports whether n events may happen at time now.
+func (m *MigratingLimiter) AllowN(now time.Time, n int64) bool {
+	serving, fed := m.serving(now)
+	if !serving.AllowN(now, n) {
+		return false
+	}
+	if fed != nil {
+		fed.AllowNWithOptions(now, n, AllowOptions{Exempt: true})
+	}
+	return true
+}
+
+// Count returns the count of the limiter making the decisions at time now.
+func (m *MigratingLimiter) Count(now time.Time) int64 {
+	serving, _ := m.serving(now)
+	return serving.Count(now)
+}
+
+// Migrated reports whether the transition is over at time now.
+func (m *MigratingLimiter) Migrated(now time.Time) bool {
+	_, fed := m.serving(now)
+	return fed == nil
+}
+
+// Stop stops both of the limiters.
+func (m *MigratingLimiter) Stop() {
+	m.mu.Lock()
+	stopOld := m.old != nil
+	m.old = nil
+	m.mu.Unlock()
+
+	// Both limiters are stopped without the lock held, since the final
+	// flushes may block.
+	if stopOld {
+		m.stopOld()
+	}
+	m.stopNew()
+}
@@ -0,0 +1,86 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMigratingLimiter(t *testing.T) {
+	newLocalLimiter := func(size time.Duration, limit int64) (*Limiter, StopFunc) {
+		return NewLimiter(size, limit, func() (Window, StopFunc) {
+			return NewLocalWindow()
+		})
+	}
+	oldLim, stopOld := newLocalLimiter(size, 10)
+	newLim, stopNew := newLocalLimiter(size/2, 5)
+
+	oldStopped := false
+	m := NewMigratingLimiter(t1, oldLim, func() {
+		oldStopped = true
+		stopOld()
+	}, newLim, stopNew)
+	defer m.Stop()
+
+	oldLim.AllowN(t0, 8)
+
+	// During the transition, the old limiter decides.
+	if ok := m.AllowN(t1, 2); !ok {
+		t.Errorf("m.AllowN(%v, 2) = %v, want: true", t1, ok)
+	}
+	if ok := m.AllowN(t2, 1); ok {
+		t.Errorf("m.AllowN(%v, 1) = %v, want: false", t2, ok)
+	}
+	if got := m.Count(t2); got != 10 {
+		t.Errorf("m.Count(%v) = %d, want: 10", t2, got)
+	}
+	// The new limiter is fed with the admitted events only.
+	if got := newLim.Count(t2); got != 2 {
+		t.Errorf("newLim.Count(%v) = %d, want: 2", t2, got)
+	}
+	// The new limiter is warmed up once its previous window is a full one,
+	// i.e. since t10 rather than one (new) window size after t1.
+	if got := m.Migrated(t6); got || oldStopped {
+		t.Errorf("m.Migrated(%v) = %v (old stopped: %v), want: false", t6, got, oldStopped)
+	}
+
+	// Once warmed up, the new limiter decides.
+	if got := m.Migrated(t10); !got || !oldStopped {
+		t.Errorf("m.Migrated(%v) = %v (old stopped: %v), want: true", t10, got, oldStopped)
+	}
+	if ok := m.AllowN(t10, 3); !ok {
+		t.Errorf("m.AllowN(%v, 3) = %v, want: true", t10, ok)
+	}
+	if got, want := m.Count(t10), newLim.Count(t10); got != want {
+		t.Errorf("m.Count(%v) = %d, want: %d", t10, got, want)
+	}
+	if got := oldLim.Count(t10); got != 10 {
+		t.Errorf("oldLim.Count(%v) = %d, want: 10", t10, got)
+	}
+}
+
+func TestMigratingLimiter_stopUnlocked(t *testing.T) {
+	oldLim, _ := NewLimiter(size, 10, newLocalWindow)
+	newLim, _ := NewLimiter(size, 10, newLocalWindow)
+
+	stopping, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	m := NewMigratingLimiter(t1, oldLim, func() {
+		close(stopping)
+		<-release
+	}, newLim, func() {})
+
+	// The old limiter is stopped, which blocks, once the transition is over.
+	go m.Migrated(t30)
+	<-stopping
+
+	done := make(chan bool)
+	go func() { done <- m.AllowN(t30, 1) }()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Errorf("m.AllowN(%v, 1) = false, want: true", t30)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("m.AllowN() blocks while the old limiter is being stopped")
+	}
+}
@@ -0,0 +1,103 @@
+package slidingwindow
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MultiSizeCounter counts the same stream of events by sliding windows of
+// several sizes (e.g. 1s, 1m and 1h) at once. Each add is recorded once
+// under a single lock, which is cheaper than feeding separate limiters, and
+// keeps the counts of all sizes consistent with each other.
+//
+// Unlike Limiter, it only counts events, and never denies them.
+type MultiSizeCounter struct {
+	mu      sync.Mutex
+	windows []sizedWindows // Sorted by size.
+}
+
+// sizedWindows is a pair of the current and previous windows of a size,
+// which are aligned as by the default aligner of Limiter.
+type sizedWindows struct {
+	aligner sizeAligner
+	curr    LocalWindow
+	prev    LocalWindow
+}
+
+// NewMultiSizeCounter creates a counter of the given sizes. The duplicate
+// and non-positive sizes are ignored.
+func NewMultiSizeCounter(sizes ...time.Duration) *MultiSizeCounter {
+	c := new(MultiSizeCounter)
+	for _, size := range sizes {
+		if size > 0 && c.find(size) == nil {
+			c.windows = append(c.windows, sizedWindows{aligner: sizeAligner{size: size}})
+			sort.Slice(c.windows, func(i, j int) bool {
+				return c.windows[i].aligner.size < c.windows[j].aligner.size
+			})
+		}
+	}
+	return c
+}
+
+// Add records n events happened at time now, for all the sizes.
+func (c *MultiSizeCounter) Add(now time.Time, n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.windows {
+		w := &c.windows[i]
+		w.advance(now)
+		w.curr.AddCount(n)
+	}
+}
+
+// Count returns the approximate count of events happened during the sliding
+// window of the given size ending at time now. It returns zero if the size
+// is not registered by NewMultiSizeCounter.
+func (c *MultiSizeCounter) Count(now time.Time, size time.Duration) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.find(size)
+	if w == nil {
+		return 0
+	}
+	w.advance(now)
+	return w.count(now)
+}
+
+func (c *MultiSizeCounter) find(size time.Duration) *sizedWindows {
+	i := sort.Search(len(c.windows), func(i int) bool {
+		return c.windows[i].aligner.size >= size
+	})
+	if i < len(c.windows) && c.windows[i].aligner.size == size {
+		return &c.windows[i]
+	}
+	return nil
+}
+
+// advance updates the current/previous windows resulting from the passage
+// of time, the same way as Limiter does.
+func (w *sizedWindows) advance(now time.Time) {
+	newCurrStart := w.aligner.Align(now)
+	if !newCurrStart.After(w.curr.Start()) {
+		return
+	}
+
+	newPrevCount := int64(0)
+	if w.aligner.Next(w.curr.Start()).Equal(newCurrStart) {
+		// The new previous-window inherits the count.
+		newPrevCount = w.curr.Count()
+	}
+	w.prev.Reset(w.aligner.Align(newCurrStart.Add(-1)), newPrevCount)
+	w.curr.Reset(newCurrStart, 0)
+}
+
+// count returns the weighted count of the sliding window ending at time now.
+// It must be called after advance.
+func (w *sizedWindows) count(now time.Time) int64 {
+	start := w.curr.Start()
+	weight := linearWeight(start, w.aligner.Next(start), now)
+	return int64(weight*float64(w.prev.Count())) + w.curr.Count()
+}
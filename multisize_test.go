@@ -0,0 +1,70 @@
+package slidingwindow
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestMultiSizeCounter(t *testing.T) {
+	sizes := []time.Duration{time.Second, time.Minute, time.Hour}
+	c := NewMultiSizeCounter(append(sizes, time.Second, 0)...)
+
+	standalone := make(map[time.Duration]*Limiter)
+	for _, size := range sizes {
+		standalone[size], _ = NewLimiter(size, math.MaxInt64, newLocalWindow)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2000; i++ {
+		now = now.Add(time.Duration(r.Int63n(int64(5 * time.Second))))
+		n := r.Int63n(10)
+
+		c.Add(now, n)
+		for _, size := range sizes {
+			standalone[size].AllowN(now, n)
+		}
+
+		if i%100 != 0 {
+			continue
+		}
+		for _, size := range sizes {
+			got, want := c.Count(now, size), standalone[size].Count(now)
+			if d := got - want; d > 1 || d < -1 {
+				t.Errorf("c.Count(%v, %v) = %d, want: %d", now, size, got, want)
+			}
+		}
+	}
+
+	if got := c.Count(now, 2*time.Second); got != 0 {
+		t.Errorf("c.Count(%v, %v) = %d, want: 0", now, 2*time.Second, got)
+	}
+}
+
+func BenchmarkMultiSizeCounter_Add(b *testing.B) {
+	c := NewMultiSizeCounter(time.Second, time.Minute, time.Hour)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Add(t0, 1)
+	}
+}
+
+func BenchmarkMultiSizeCounter_Standalone(b *testing.B) {
+	var limiters []*Limiter
+	for _, size := range []time.Duration{time.Second, time.Minute, time.Hour} {
+		lim, _ := NewLimiter(size, math.MaxInt64, newLocalWindow)
+		limiters = append(limiters, lim)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, lim := range limiters {
+			lim.AllowN(t0, 1)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package slidingwindow
+
+// OversizePolicy decides how to handle the events whose number exceeds the
+// limit outright, which can never fit into the limit.
+type OversizePolicy int
+
+const (
+	// RejectAlways denies the oversize events, which is the default.
+	RejectAlways OversizePolicy = iota
+
+	// AdmitOnce admits the oversize events once the count drops to zero,
+	// and then lets them decay as usual. This is useful for e.g. a limiter
+	// of uploaded bytes, where a single huge upload exceeding the limit
+	// should not be stuck forever.
+	AdmitOnce
+)
+
+// WithOversizePolicy sets the policy for the events whose number exceeds
+// the limit outright.
+func WithOversizePolicy(p OversizePolicy) Option {
+	return func(lim *Limiter) {
+		lim.oversize = p
+	}
+}
+
+// admitOversize reports whether n oversize events are admitted at the given
+// count by the policy.
+func (lim *Limiter) admitOversize(n, count, limit int64) bool {
+	return lim.oversize == AdmitOnce && n > limit && count == 0
+}
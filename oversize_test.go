@@ -0,0 +1,72 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_WithOversizePolicy(t *testing.T) {
+	type step struct {
+		t    time.Time
+		n    int64
+		want bool
+	}
+	cases := []struct {
+		name   string
+		policy OversizePolicy
+		steps  []step
+	}{
+		{
+			name:   "reject always",
+			policy: RejectAlways,
+			steps: []step{
+				{t0, 15, false},
+				{t0.Add(3 * size), 15, false},
+				{t0.Add(3 * size), 10, true},
+			},
+		},
+		{
+			name:   "admit once",
+			policy: AdmitOnce,
+			steps: []step{
+				{t0, 15, true},
+				{t1, 1, false},
+				{t0.Add(size + 5*d), 15, false}, // count is 7
+				{t0.Add(2 * size), 15, true},    // decayed to zero
+				{t0.Add(2*size + d), 1, false},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lim, stop := NewLimiter(size, limit, newLocalWindow, WithOversizePolicy(c.policy))
+			defer stop()
+
+			for _, s := range c.steps {
+				if got := lim.AllowN(s.t, s.n); got != s.want {
+					t.Errorf("AllowN(%v, %d) = %v, want: %v", s.t.Sub(t0), s.n, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter_WithOversizePolicy_delay(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, newLocalWindow)
+	if _, ok := lim.delayN(t0, 15); ok {
+		t.Errorf("lim.delayN(%v, 15) ok = true, want: false", t0)
+	}
+
+	lim, _ = NewLimiter(size, limit, newLocalWindow, WithOversizePolicy(AdmitOnce))
+	if delay, ok := lim.delayN(t0, 15); !ok || delay != 0 {
+		t.Errorf("lim.delayN(%v, 15) = (%v, %v), want: (0, true)", t0, delay, ok)
+	}
+	lim.AllowN(t0, 15)
+	delay, ok := lim.delayN(t5, 15)
+	if !ok || delay <= size || delay > 2*size-5*d {
+		t.Fatalf("lim.delayN(%v, 15) = (%v, %v), want: (delay in (%v, %v], true)", t5, delay, ok, size, 2*size-5*d)
+	}
+	if !lim.AllowN(t5.Add(delay), 15) {
+		t.Errorf("lim.AllowN(%v, 15) = false, want: true", t5.Add(delay))
+	}
+}
@@ -0,0 +1,89 @@
+package slidingwindow
+
+import (
+	"math"
+	"time"
+)
+
+// Pause freezes the limiter as of the latest time it has been used, e.g.
+// during planned maintenance: the count no longer decays, and the events
+// are no longer counted (while still being decided by the frozen count),
+// until Resume is called.
+func (lim *Limiter) Pause() {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if lim.paused {
+		return
+	}
+	lim.paused, lim.pausedAt = true, lim.lastSeen
+}
+
+// Resume unfreezes the limiter at time now, with the windows re-anchored at
+// now as if no time had passed during the pause, so that the count right
+// after resuming equals the one before pausing.
+//
+// The current window is mapped onto the window containing now, and the
+// previous window onto the one before it. Since the weight of the previous
+// window at now may differ from that at the pause, its count is rescaled to
+// keep the weighted count unchanged, and then decays over the rest of the
+// current window instead. The rescaled count never exceeds the original one,
+// and the rest of the weighted count is folded into the current window.
+//
+// Once re-anchored, the outstanding reservations can no longer be refunded,
+// and are kept for good as if committed. The subscribers of Subscribe receive
+// the sample of the paused window at once, while the count carried over is
+// not reported again with the window it is carried into.
+//
+// Pause and Resume are local-only: on a SyncWindow, the re-anchored counts
+// are never synced to the central datastore, since the shared counts are
+// not frozen along with the limiter. Thus the count converges to the shared
+// one on the next sync.
+func (lim *Limiter) Resume(now time.Time) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if !lim.paused {
+		return
+	}
+
+	start := lim.curr.Start()
+	weightedPrev := lim.weighted(lim.prev.Count(), 0, start, lim.aligner.Next(start), lim.pausedAt)
+	lim.paused = false
+
+	newCurrStart := lim.aligner.Align(now)
+	newPrevStart := lim.aligner.Align(newCurrStart.Add(-1))
+	newCurrEnd := lim.aligner.Next(newCurrStart)
+	currCount := lim.curr.Count()
+
+	var prevCount int64
+	if w := lim.weight(newCurrStart, newCurrEnd, now); w > 0 {
+		prevCount = int64(math.Ceil(float64(weightedPrev)/w - 1e-9))
+		// A weight near zero would inflate the count wildly.
+		if prevCount > lim.prev.Count() {
+			prevCount = lim.prev.Count()
+		}
+	}
+	// Fold what the previous window no longer counts into the current one.
+	carried := weightedPrev - lim.weighted(prevCount, 0, newCurrStart, newCurrEnd, now)
+	currCount += carried
+
+	lim.prev.Reset(newPrevStart, prevCount)
+	if newCurrStart.Equal(start) {
+		lim.seedLocal(currCount)
+		lim.reservedPrev = minInt64(lim.reservedPrev, prevCount)
+		lim.carried += carried
+	} else {
+		lim.reservedPrev, lim.reservedCurr = 0, 0
+		lim.publish()
+		if newCurrStart.After(start) {
+			lim.demand.Advance(newPrevStart, newCurrStart, true)
+		}
+
+		lim.curr.Reset(newCurrStart, currCount)
+		lim.carried = currCount
+	}
+
+	lim.lastSeen = now
+	lim.gen++
+}
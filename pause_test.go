@@ -0,0 +1,152 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_PauseResume(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, newLocalWindow)
+	lim.AllowN(t0, 4)
+	lim.AllowN(t15, 3)
+
+	lim.Pause()
+
+	// Neither decaying nor counting during the pause.
+	if !lim.AllowN(t18, 1) {
+		t.Errorf("lim.AllowN(%v, 1) = false, want: true", t18)
+	}
+	if got := lim.Count(t18); got != 4/2+3 {
+		t.Errorf("lim.Count(%v) during the pause = %d, want: %d", t18, got, 4/2+3)
+	}
+
+	// Resume after a long pause.
+	resumed := t0.Add(10*time.Hour + 2*d)
+	lim.Resume(resumed)
+	if got := lim.Count(resumed); got != 4/2+3 {
+		t.Errorf("lim.Count(%v) after resuming = %d, want: %d", resumed, got, 4/2+3)
+	}
+	if start, want := lim.CurrentWindowForTest().Start(), resumed.Truncate(size); !start.Equal(want) {
+		t.Errorf("the current window starts at %v, want: %v", start, want)
+	}
+
+	// Afterwards, the count decays and counts as usual.
+	// I.e. 3 * 8/10, where 3 is the count of the re-anchored window.
+	if got := lim.Count(resumed.Add(size)); got != 2 {
+		t.Errorf("lim.Count() a window later = %d, want: 2", got)
+	}
+	lim.AllowN(resumed.Add(size), 1)
+	if got := lim.Count(resumed.Add(size)); got != 3 {
+		t.Errorf("lim.Count() after an add = %d, want: 3", got)
+	}
+}
+
+func TestLimiter_Resume_beforeBoundary(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, newLocalWindow)
+	lim.AllowN(t0, 4)
+	lim.AllowN(t15, 3)
+	lim.Pause()
+
+	// The weight of the previous window is near zero right before
+	// the boundary.
+	resumed := t0.Add(10*time.Hour + size - time.Millisecond)
+	lim.Resume(resumed)
+	if got := lim.Count(resumed); got != 4/2+3 {
+		t.Errorf("lim.Count(%v) after resuming = %d, want: %d", resumed, got, 4/2+3)
+	}
+	if got := lim.PreviousWindowForTest().Count(); got > 4 {
+		t.Errorf("the count of the previous window = %d, want: <= 4", got)
+	}
+
+	// The folded count becomes the previous one at the boundary.
+	if got := lim.Count(resumed.Add(time.Millisecond)); got != 4/2+3 {
+		t.Errorf("lim.Count() at the boundary = %d, want: %d", got, 4/2+3)
+	}
+}
+
+func TestLimiter_Resume_syncWindow(t *testing.T) {
+	store := newMemDatastore()
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+	})
+	lim.AllowN(t1, 3)
+	lim.Pause()
+
+	resumed := t0.Add(10*time.Hour + 2*d)
+	lim.Resume(resumed)
+
+	// The count carried over is kept locally, but never synced.
+	if got := lim.Count(resumed); got != 3 {
+		t.Errorf("lim.Count(%v) = %d, want: 3", resumed, got)
+	}
+	lim.AllowN(resumed, 0)
+	if got, _ := store.Get("test", resumed.Truncate(size).UnixNano()); got != 0 {
+		t.Errorf("store.Get() = %d, want: 0", got)
+	}
+	// Then the count converges to the shared one.
+	if got := lim.Count(resumed); got != 0 {
+		t.Errorf("lim.Count(%v) = %d, want: 0", resumed, got)
+	}
+
+}
+
+func TestLimiter_Resume_syncWindowBeforeBoundary(t *testing.T) {
+	store := newMemDatastore()
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+	})
+	lim.AllowN(t1, 4)
+	lim.AllowN(t12, 2)
+	lim.Pause()
+
+	// weighted-prev: 4 * 8/10 = 3, of which 4 * 5/10 = 2 is still counted
+	// by the previous window, and the rest 1 is carried over locally.
+	lim.Resume(t15)
+	if got := lim.Count(t15); got != 5 {
+		t.Errorf("lim.Count(%v) = %d, want: 5", t15, got)
+	}
+	lim.AllowN(t15, 0)
+	if got, _ := store.Get("test", t10.UnixNano()); got != 2 {
+		t.Errorf("store.Get() = %d, want: 2", got)
+	}
+}
+
+func TestLimiter_Resume_reservationsAndSubscribers(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, newLocalWindow)
+	ch := lim.Subscribe()
+
+	lim.AllowN(t1, 3)
+	r := lim.ReserveN(t1, 2)
+	lim.Pause()
+
+	resumed := t0.Add(10*time.Hour + 2*d)
+	lim.Resume(resumed)
+
+	// The outstanding reservations are kept for good.
+	r.CancelAt(resumed)
+	if got := lim.Count(resumed); got != 5 {
+		t.Errorf("lim.Count() = %d, want: 5", got)
+	}
+	if got := lim.CountIncludingReservations(resumed); got != 5 {
+		t.Errorf("lim.CountIncludingReservations() = %d, want: 5", got)
+	}
+
+	// The paused window is reported at once, while the count carried over
+	// is not reported again.
+	lim.AllowN(resumed, 1)
+	lim.AllowN(resumed.Add(size), 0)
+	want := []WindowSample{
+		{Start: t0, End: t10, Count: 5},
+		{Start: resumed.Truncate(size), End: resumed.Truncate(size).Add(size), Count: 1},
+	}
+	for i, w := range want {
+		select {
+		case got := <-ch:
+			if got != w {
+				t.Errorf("sample #%d = %+v, want: %+v", i, got, w)
+			}
+		default:
+			t.Fatalf("sample #%d = none, want: %+v", i, w)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package slidingwindow
+
+import (
+	"sync"
+	"time"
+)
+
+var limiterPool = sync.Pool{
+	New: func() interface{} {
+		return new(Limiter)
+	},
+}
+
+// AcquireLimiter is like NewLimiter, but reuses a limiter released by
+// ReleaseLimiter if possible, which reduces GC pressure for workloads
+// creating and destroying many short-lived limiters.
+//
+// The limiter must be released by ReleaseLimiter, rather than a stop
+// function, once it is no longer needed.
+func AcquireLimiter(size time.Duration, limit int64, newWindow NewWindow, opts ...Option) *Limiter {
+	lim := limiterPool.Get().(*Limiter)
+
+	prevWin, ok := lim.prev.(*LocalWindow)
+	if ok {
+		prevWin.Reset(time.Unix(0, 0), 0)
+	} else {
+		prevWin, _ = NewLocalWindow()
+	}
+
+	lim.init(size, limit, newWindow, prevWin, opts)
+	return lim
+}
+
+// ReleaseLimiter stops the possible sync behaviour within lim, clears all
+// of its states, and then puts it back for reuse. It is unsafe to use lim
+// after it has been released.
+func ReleaseLimiter(lim *Limiter) {
+	lim.stop()
+
+	// Keep the previous window, which is always a LocalWindow, for reuse.
+	*lim = Limiter{prev: lim.prev}
+	limiterPool.Put(lim)
+}
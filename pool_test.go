@@ -0,0 +1,56 @@
+package slidingwindow
+
+import (
+	"testing"
+)
+
+func newLocalWindow() (Window, StopFunc) {
+	return NewLocalWindow()
+}
+
+func TestAcquireLimiter(t *testing.T) {
+	stopped := 0
+	lim := AcquireLimiter(size, limit, func() (Window, StopFunc) {
+		w, _ := NewLocalWindow()
+		return w, func() { stopped++ }
+	}, WithSoftLimit(5))
+	lim.AllowN(t0, 5)
+	lim.AllowN(t10, 3)
+
+	ReleaseLimiter(lim)
+	if stopped != 1 {
+		t.Errorf("stopped = %d, want: 1", stopped)
+	}
+
+	// A limiter acquired (possibly reused) afterwards is always clean.
+	lim = AcquireLimiter(size, 2*limit, newLocalWindow)
+	defer ReleaseLimiter(lim)
+
+	if got := lim.Count(t12); got != 0 {
+		t.Errorf("lim.Count(%v) = %d, want: 0", t12, got)
+	}
+	if got := lim.Limit(); got != 2*limit {
+		t.Errorf("lim.Limit() = %d, want: %d", got, 2*limit)
+	}
+	if lim.softLimit != 0 || lim.closed {
+		t.Errorf("lim has stale states: softLimit=%d, closed=%v", lim.softLimit, lim.closed)
+	}
+}
+
+func BenchmarkNewLimiter(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lim, stop := NewLimiter(size, limit, newLocalWindow)
+		lim.AllowN(t1, 1)
+		stop()
+	}
+}
+
+func BenchmarkAcquireLimiter(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lim := AcquireLimiter(size, limit, newLocalWindow)
+		lim.AllowN(t1, 1)
+		ReleaseLimiter(lim)
+	}
+}
@@ -0,0 +1,60 @@
+package slidingwindow
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+type priorityBand struct {
+	priority int
+	fraction float64
+}
+
+// WithPriorityBands sets the thresholds of the priorities, each of which is
+// a fraction of the limit, at which the events of the priority are denied.
+// E.g. with bands {0: 0.8, 10: 1.1}, the events of priority 0 (the default
+// one) are denied at 80% of the limit, while those of priority 10 may even
+// overshoot the limit by 10%, which lets the critical traffic through during
+// near-limit conditions.
+//
+// A priority without its own band takes the band of the highest priority
+// below it. If there is none, the threshold is the limit itself.
+func WithPriorityBands(bands map[int]float64) Option {
+	return func(lim *Limiter) {
+		lim.bands = lim.bands[:0]
+		for p, f := range bands {
+			lim.bands = append(lim.bands, priorityBand{priority: p, fraction: f})
+		}
+		sort.Slice(lim.bands, func(i, j int) bool {
+			return lim.bands[i].priority < lim.bands[j].priority
+		})
+	}
+}
+
+// AllowNPriority is like AllowN, but the threshold at which the events are
+// denied depends on their priority, see WithPriorityBands.
+func (lim *Limiter) AllowNPriority(now time.Time, n int64, prio int) bool {
+	return lim.AllowNWithOptions(now, n, AllowOptions{Priority: prio})
+}
+
+// priorityLimit returns the threshold of the given priority. It must be
+// called with the lock held.
+func (lim *Limiter) priorityLimit(prio int) int64 {
+	// Find the first band above prio.
+	i := sort.Search(len(lim.bands), func(i int) bool {
+		return lim.bands[i].priority > prio
+	})
+	if i == 0 {
+		return lim.limit
+	}
+
+	l := math.Floor(float64(lim.limit) * lim.bands[i-1].fraction)
+	switch {
+	case l >= math.MaxInt64:
+		return math.MaxInt64
+	case !(l > 0):
+		return 0
+	}
+	return int64(l)
+}
@@ -0,0 +1,42 @@
+package slidingwindow
+
+import (
+	"testing"
+)
+
+func TestLimiter_AllowNPriority(t *testing.T) {
+	const (
+		low  = -1
+		high = 10
+	)
+	lim, _ := NewLimiter(size, 100, newLocalWindow, WithPriorityBands(map[int]float64{
+		low:  0.8,
+		0:    1,
+		high: 1.1,
+	}))
+
+	steps := []struct {
+		n         int64
+		prio      int
+		want      bool
+		wantCount int64
+	}{
+		{70, 0, true, 70},
+		{11, low, false, 70}, // 81 > 80
+		{11, 0, true, 81},
+		{11, 5, true, 92},          // takes the band of priority 0
+		{9, 0, false, 92},          // 101 > 100
+		{9, high, true, 101},       // the high priority overshoots the limit
+		{10, high + 1, false, 101}, // takes the band of priority 10, 111 > 110
+		{9, high + 1, true, 110},
+		{1, -5, false, 110}, // below all bands, the limit itself
+	}
+	for _, s := range steps {
+		if got := lim.AllowNPriority(t0, s.n, s.prio); got != s.want {
+			t.Errorf("lim.AllowNPriority(%d, %d) = %v, want: %v", s.n, s.prio, got, s.want)
+		}
+		if got := lim.Count(t0); got != s.wantCount {
+			t.Errorf("after AllowNPriority(%d, %d): count = %d, want: %d", s.n, s.prio, got, s.wantCount)
+		}
+	}
+}
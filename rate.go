@@ -0,0 +1,33 @@
+package slidingwindow
+
+import (
+	"math"
+	"time"
+)
+
+// LimitForRate returns the limit per window size, which permits a given
+// sustained rate (in events per second).
+//
+// For events happening at a steady rate, the previous window and the current
+// one hold the same rate, so the weighted count stays at about rate * size,
+// regardless of how far the sliding window has moved into the current one.
+// However, right at a window boundary, the count still covers the events of
+// the full previous window, besides the new one. Hence the limit is one more
+// than rate * size (rounded down).
+//
+// Note that the limit also caps the bursts: after an idle period of two
+// windows, up to limit events may happen at once, which are then followed
+// by a period of less than the sustained rate until they decay. A smaller
+// size lowers the burst for the same rate, at the cost of a coarser
+// approximation of the rate.
+func LimitForRate(rate float64, size time.Duration) int64 {
+	if rate <= 0 || size <= 0 {
+		return 0
+	}
+	// Tolerate the float error, e.g. 0.29 * 100 = 28.999999999999996.
+	limit := math.Floor(rate*size.Seconds()+1e-9) + 1
+	if limit >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(limit)
+}
@@ -0,0 +1,41 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimitForRate(t *testing.T) {
+	cases := []struct {
+		rate float64
+		size time.Duration
+		want int64
+	}{
+		{100, time.Second, 101},
+		{100, time.Minute, 6001},
+		{0.1, 30 * time.Second, 4},
+		{0.29, 100 * time.Second, 30},
+		{2.5, time.Second, 3},
+		{10, 150 * time.Millisecond, 2},
+		{0, time.Second, 0},
+		{10, 0, 0},
+	}
+	for _, c := range cases {
+		if got := LimitForRate(c.rate, c.size); got != c.want {
+			t.Errorf("LimitForRate(%v, %v) = %d, want: %d", c.rate, c.size, got, c.want)
+		}
+	}
+}
+
+func TestLimitForRate_sustained(t *testing.T) {
+	// Events at 50/s, i.e. one per 20ms, are all admitted in the long run,
+	// while a limit of merely 50 would deny the event right at the boundary.
+	lim, _ := NewLimiter(size, LimitForRate(50, size), newLocalWindow)
+
+	for i := 0; i < 500; i++ {
+		now := t0.Add(time.Duration(i) * 20 * time.Millisecond)
+		if !lim.AllowN(now, 1) {
+			t.Fatalf("event %d denied at %v", i, now)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package slidingwindow
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// LimiterReport describes the usage of a limiter.
+type LimiterReport struct {
+	Key           string
+	WeightedCount int64
+	Limit         int64
+	// Utilization is WeightedCount divided by Limit. For a non-positive
+	// Limit, it is 0 if WeightedCount is 0, and +Inf otherwise.
+	Utilization float64
+}
+
+// Report returns the usage of the given limiters at time now, sorted by
+// utilization in descending order (and then by key), which is convenient
+// for top-N reporting.
+func Report(limiters map[string]*Limiter, now time.Time) []LimiterReport {
+	reports := make([]LimiterReport, 0, len(limiters))
+	for key, lim := range limiters {
+		r := LimiterReport{
+			Key:           key,
+			WeightedCount: lim.Count(now),
+			Limit:         lim.Limit(),
+		}
+		switch {
+		case r.Limit > 0:
+			r.Utilization = float64(r.WeightedCount) / float64(r.Limit)
+		case r.WeightedCount > 0:
+			r.Utilization = math.Inf(1)
+		}
+		reports = append(reports, r)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Utilization != reports[j].Utilization {
+			return reports[i].Utilization > reports[j].Utilization
+		}
+		return reports[i].Key < reports[j].Key
+	})
+	return reports
+}
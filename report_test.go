@@ -0,0 +1,49 @@
+package slidingwindow
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	newLimiter := func(limit, n int64) *Limiter {
+		lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+			return NewLocalWindow()
+		})
+		lim.AllowN(t1, n)
+		return lim
+	}
+
+	got := Report(map[string]*Limiter{
+		"a": newLimiter(10, 5),
+		"b": newLimiter(20, 15),
+		"c": newLimiter(4, 2),
+		"d": newLimiter(100, 0),
+		"e": newLimiter(0, 0),
+	}, t2)
+
+	want := []LimiterReport{
+		{"b", 15, 20, 0.75},
+		{"a", 5, 10, 0.5},
+		{"c", 2, 4, 0.5},
+		{"d", 0, 100, 0},
+		{"e", 0, 0, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Report() = %+v, want: %+v", got, want)
+	}
+}
+
+func TestReport_ZeroLimit(t *testing.T) {
+	lim, _ := NewLimiter(size, 10, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+	lim.AllowN(t1, 5)
+	lim.SetLimit(0)
+
+	got := Report(map[string]*Limiter{"a": lim}, t2)
+	if len(got) != 1 || !math.IsInf(got[0].Utilization, 1) {
+		t.Errorf("Report() = %+v, want: +Inf utilization", got)
+	}
+}
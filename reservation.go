@@ -0,0 +1,177 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// Reservation holds information about events that are permitted by
+// a limiter to happen after a delay.
+type Reservation struct {
+	lim *Limiter
+	ok  bool
+	n   int64
+
+	// The start boundary of the window the events are counted in.
+	start time.Time
+	// The time at which the events are permitted to happen.
+	timeToAct time.Time
+
+	state reservationState
+}
+
+type reservationState int
+
+const (
+	reservationPending reservationState = iota
+	reservationCommitted
+	reservationCanceled
+)
+
+// Reserve is shorthand for ReserveN(time.Now(), 1).
+func (lim *Limiter) Reserve() *Reservation {
+	return lim.ReserveN(time.Now(), 1)
+}
+
+// ReserveN returns a Reservation that indicates how long the caller must
+// wait before n events happen. The events are counted immediately, so that
+// subsequent calls will take them into consideration.
+//
+// The reservation stays outstanding until it is committed by Commit, or
+// refunded by Cancel.
+//
+// If n exceeds the limit, the returned Reservation is not OK and nothing
+// is counted.
+func (lim *Limiter) ReserveN(now time.Time, n int64) *Reservation {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+
+	delay, ok := lim.delay(now, n)
+	if !ok {
+		return &Reservation{lim: lim, n: n}
+	}
+
+	// Trigger the possible sync behaviour.
+	defer lim.sync(now)
+	lim.gen++
+
+	lim.curr.AddCount(n)
+	lim.reservedCurr += n
+	return &Reservation{
+		lim:       lim,
+		ok:        true,
+		n:         n,
+		start:     lim.curr.Start(),
+		timeToAct: now.Add(delay),
+	}
+}
+
+// OK returns whether the limiter can permit the events within the maximum
+// wait time.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay is shorthand for DelayFrom(time.Now()).
+func (r *Reservation) Delay() time.Duration {
+	return r.DelayFrom(time.Now())
+}
+
+// DelayFrom returns the duration for which the reservation holder must wait
+// before taking the reserved action, starting from time now.
+func (r *Reservation) DelayFrom(now time.Time) time.Duration {
+	if !r.ok {
+		return InfDuration
+	}
+	delay := r.timeToAct.Sub(now)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Commit indicates that the reservation holder has taken the reserved
+// action, thus the reserved count is kept for good.
+func (r *Reservation) Commit() {
+	lim := r.lim
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if !r.ok || r.state != reservationPending {
+		return
+	}
+	r.state = reservationCommitted
+
+	if _, outstanding := lim.reservationWindow(r.start); outstanding != nil {
+		*outstanding -= minInt64(r.n, *outstanding)
+		lim.gen++
+	}
+}
+
+// Cancel is shorthand for CancelAt(time.Now()).
+func (r *Reservation) Cancel() {
+	r.CancelAt(time.Now())
+}
+
+// CancelAt indicates that the reservation holder will not take the reserved
+// action, and refunds the reserved count as of time now, as long as it is
+// still counted in the current or the previous window. The refund never
+// makes the window's count negative.
+//
+// A reservation can only be canceled if it is neither committed nor
+// canceled before.
+func (r *Reservation) CancelAt(now time.Time) {
+	lim := r.lim
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if !r.ok || r.state != reservationPending {
+		return
+	}
+	r.state = reservationCanceled
+
+	lim.advance(now)
+
+	w, outstanding := lim.reservationWindow(r.start)
+	if w == nil {
+		// The window has expired, there is nothing to refund.
+		return
+	}
+
+	// Never refund more than what is still outstanding.
+	n := minInt64(r.n, *outstanding)
+	*outstanding -= n
+
+	n = minInt64(n, w.Count())
+	if w == lim.curr {
+		lim.curr.AddCount(-n)
+	} else {
+		lim.prev.Reset(lim.prev.Start(), lim.prev.Count()-n)
+	}
+	lim.gen++
+}
+
+// reservationWindow returns the window in which the reservation starting
+// at start is counted, along with the outstanding count of the window.
+// It returns nil if the window has expired.
+func (lim *Limiter) reservationWindow(start time.Time) (Window, *int64) {
+	switch {
+	case lim.curr.Start().Equal(start):
+		return lim.curr, &lim.reservedCurr
+	case lim.prev.Start().Equal(start):
+		return lim.prev, &lim.reservedPrev
+	default:
+		return nil, nil
+	}
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// InfDuration is the duration returned by Delay when a Reservation is not OK.
+const InfDuration = time.Duration(1<<63 - 1)
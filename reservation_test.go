@@ -0,0 +1,154 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_ReserveN(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	r1 := lim.ReserveN(t0, 10)
+	if !r1.OK() || r1.DelayFrom(t0) != 0 {
+		t.Fatalf("r1 = (%v, %v), want: (true, 0)", r1.OK(), r1.DelayFrom(t0))
+	}
+
+	// Wait until the next window, where (10 * weight + 5) <= 10,
+	// i.e. weight <= 1/2.
+	r2 := lim.ReserveN(t5, 5)
+	if want := 10 * d; !r2.OK() || r2.DelayFrom(t5) != want {
+		t.Errorf("r2 = (%v, %v), want: (true, %v)", r2.OK(), r2.DelayFrom(t5), want)
+	}
+	if got := lim.CountIncludingReservations(t5); got != 15 {
+		t.Errorf("lim.CountIncludingReservations(%v) = %d, want: 15", t5, got)
+	}
+
+	r2.CancelAt(t6)
+	r2.CancelAt(t6) // no-op
+	if got := lim.CountIncludingReservations(t6); got != 10 {
+		t.Errorf("lim.CountIncludingReservations(%v) = %d, want: 10", t6, got)
+	}
+
+	r3 := lim.ReserveN(t6, 11)
+	if r3.OK() || r3.DelayFrom(t6) != InfDuration {
+		t.Errorf("r3 = (%v, %v), want: (false, InfDuration)", r3.OK(), r3.DelayFrom(t6))
+	}
+}
+
+func TestReservation_Commit(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	r := lim.ReserveN(t1, 4)
+	r.Commit()
+	r.CancelAt(t2) // no-op, since it has been committed
+	if got := lim.Count(t2); got != 4 {
+		t.Errorf("lim.Count(%v) = %d, want: 4", t2, got)
+	}
+	if lim.reservedCurr != 0 {
+		t.Errorf("lim.reservedCurr = %d, want: 0", lim.reservedCurr)
+	}
+}
+
+func TestReservation_CancelAt(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	// Cancellation within the same window.
+	r1 := lim.ReserveN(t1, 4)
+	r1.CancelAt(t2)
+	if got := lim.Count(t2); got != 0 {
+		t.Errorf("lim.Count(%v) = %d, want: 0", t2, got)
+	}
+
+	// Cancellation after the window has become the previous one.
+	lim.AllowN(t2, 2)
+	r2 := lim.ReserveN(t3, 4)
+	r2.CancelAt(t15)
+	if got, want := lim.Count(t15), int64(2/2); got != want {
+		t.Errorf("lim.Count(%v) = %d, want: %d", t15, got, want)
+	}
+
+	// The refund is floored at zero.
+	r3 := lim.ReserveN(t15, 5)
+	lim.DrainCurrent(t15)
+	lim.AllowN(t16, 1)
+	r3.CancelAt(t16)
+	if got := lim.curr.Count(); got != 0 {
+		t.Errorf("lim.curr.Count() = %d, want: 0", got)
+	}
+
+	// Cancellation after the window has expired.
+	r4 := lim.ReserveN(t16, 3)
+	r4.CancelAt(t30)
+	if lim.reservedCurr != 0 || lim.reservedPrev != 0 {
+		t.Errorf("outstanding = (%d, %d), want: (0, 0)", lim.reservedPrev, lim.reservedCurr)
+	}
+}
+
+func TestLimiter_CountIncludingReservations(t *testing.T) {
+	lim, _ := NewLimiter(size, 100, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	lim.AllowN(t1, 10)
+	r1 := lim.ReserveN(t1, 5)
+	r2 := lim.ReserveN(t2, 6)
+	r3 := lim.ReserveN(t3, 7)
+	r1.Commit()
+
+	cases := []struct {
+		now           time.Time
+		wantCount     int64
+		wantIncluding int64
+	}{
+		// 10 + 5 committed, 6 + 7 outstanding.
+		{t3, 15, 28},
+		// The same counts with a weight of 1/2.
+		{t15, 15 / 2, 28 / 2},
+	}
+	for _, c := range cases {
+		if got := lim.Count(c.now); got != c.wantCount {
+			t.Errorf("lim.Count(%v) = %d, want: %d", c.now, got, c.wantCount)
+		}
+		if got := lim.CountIncludingReservations(c.now); got != c.wantIncluding {
+			t.Errorf("lim.CountIncludingReservations(%v) = %d, want: %d", c.now, got, c.wantIncluding)
+		}
+	}
+
+	// Both views agree once all the reservations are settled.
+	r2.Commit()
+	r3.CancelAt(t15)
+	if got, want := lim.Count(t15), int64(21/2); got != want {
+		t.Errorf("lim.Count(%v) = %d, want: %d", t15, got, want)
+	}
+	if got, want := lim.CountIncludingReservations(t15), int64(21/2); got != want {
+		t.Errorf("lim.CountIncludingReservations(%v) = %d, want: %d", t15, got, want)
+	}
+}
+
+func TestReservation_CancelAt_syncWindow(t *testing.T) {
+	store := newMemDatastore()
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+	})
+
+	r := lim.ReserveN(t0, 5)
+	r.CancelAt(t1)
+	if got := lim.CountIncludingReservations(t1); got != 0 {
+		t.Errorf("lim.CountIncludingReservations(%v) = %d, want: 0", t1, got)
+	}
+
+	// The refund is synced, instead of being undone by the next sync.
+	lim.AllowN(t2, 0)
+	if got := lim.CountIncludingReservations(t2); got != 0 {
+		t.Errorf("lim.CountIncludingReservations(%v) = %d, want: 0", t2, got)
+	}
+	if got, _ := store.Get("test", t0.UnixNano()); got != 0 {
+		t.Errorf("store.Get() = %d, want: 0", got)
+	}
+}
@@ -0,0 +1,59 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// sharedResetter is implemented by synchronizers which can reset the count
+// in the central datastore.
+type sharedResetter interface {
+	resetShared(key string, start int64) (epoch int64, err error)
+}
+
+// windowResetter is implemented by windows which can reset the count shared
+// across nodes, where the state of the window is only accessed within locked.
+type windowResetter interface {
+	resetShared(locked func(f func())) error
+}
+
+// ResetShared advances the limiter to time now, and then resets its count,
+// across all nodes sharing the central datastore, which must implement
+// EpochDatastore. The other nodes observe the reset on their next sync, and
+// drop their local changes made before it, thus the shared count will not
+// be re-inflated.
+//
+// Note that only the count of the current window is shared. The previous
+// window is cleared on this node, but decays as usual on the other nodes.
+// For a window without sync behaviour (e.g. LocalWindow), ResetShared just
+// resets the local count.
+//
+// Like Flush, the lock of the limiter is not held during the exchange with
+// the central datastore.
+func (lim *Limiter) ResetShared(now time.Time) error {
+	lim.mu.Lock()
+	if lim.closed {
+		lim.mu.Unlock()
+		return ErrClosed
+	}
+
+	lim.advance(now)
+	r, shared := lim.curr.(windowResetter)
+	if !shared {
+		lim.curr.Reset(lim.curr.Start(), 0)
+	}
+	lim.mu.Unlock()
+
+	if shared {
+		if err := r.resetShared(lim.locked); err != nil {
+			return err
+		}
+	}
+
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.prev.Reset(lim.prev.Start(), 0)
+	lim.reservedPrev, lim.reservedCurr = 0, 0
+	lim.gen++
+	return nil
+}
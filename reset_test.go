@@ -0,0 +1,144 @@
+package slidingwindow
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// epochMemDatastore is a MemDatastore which also supports coordinated resets.
+type epochMemDatastore struct {
+	*MemDatastore
+
+	mu     sync.Mutex
+	epochs map[string]int64
+}
+
+func newEpochMemDatastore() *epochMemDatastore {
+	return &epochMemDatastore{
+		MemDatastore: newMemDatastore(),
+		epochs:       make(map[string]int64),
+	}
+}
+
+func (d *epochMemDatastore) AddInEpoch(key string, start, delta, epoch int64) (int64, int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	currEpoch := d.epochs[key]
+	if epoch >= 0 && epoch != currEpoch {
+		delta = 0
+	}
+	count, err := d.MemDatastore.Add(key, start, delta)
+	return count, currEpoch, err
+}
+
+func (d *epochMemDatastore) Reset(key string, start int64) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.MemDatastore.mu.Lock()
+	delete(d.MemDatastore.data, d.fullKey(key, start))
+	d.MemDatastore.mu.Unlock()
+
+	d.epochs[key]++
+	return d.epochs[key], nil
+}
+
+func TestLimiter_ResetShared(t *testing.T) {
+	size := time.Hour
+	base := time.Now().Truncate(size)
+	at := func(d time.Duration) time.Time { return base.Add(d) }
+
+	store := newEpochMemDatastore()
+	newLimiter := func() *Limiter {
+		lim, _ := NewLimiter(size, 100, func() (Window, StopFunc) {
+			return NewSyncWindow("test", NewBlockingSynchronizer(store, time.Second))
+		})
+		return lim
+	}
+	a, b := newLimiter(), newLimiter()
+
+	a.AllowN(at(0), 5)
+	b.AllowN(at(0), 3)
+	a.AllowN(at(time.Second), 0)
+	if got := a.Count(at(time.Second)); got != 8 {
+		t.Fatalf("a.Count(%v) = %d, want: 8", at(time.Second), got)
+	}
+
+	// An unsynced change made by b before the reset.
+	b.AllowN(at(500*time.Millisecond), 2)
+
+	if err := a.ResetShared(at(1100 * time.Millisecond)); err != nil {
+		t.Fatalf("a.ResetShared() err: %v", err)
+	}
+	if got := a.Count(at(1100 * time.Millisecond)); got != 0 {
+		t.Errorf("a.Count(%v) = %d, want: 0", at(1100*time.Millisecond), got)
+	}
+
+	// b observes the reset on its next sync, and drops its change.
+	b.AllowN(at(1200*time.Millisecond), 0)
+	if got := b.Count(at(1200 * time.Millisecond)); got != 0 {
+		t.Errorf("b.Count(%v) = %d, want: 0", at(1200*time.Millisecond), got)
+	}
+
+	// The changes made after the reset are shared as usual.
+	b.AllowN(at(2300*time.Millisecond), 1)
+	a.AllowN(at(2300*time.Millisecond), 0)
+	if got := a.Count(at(2300 * time.Millisecond)); got != 1 {
+		t.Errorf("a.Count(%v) = %d, want: 1", at(2300*time.Millisecond), got)
+	}
+}
+
+func TestLimiter_ResetShared_notSupported(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(newMemDatastore(), time.Second))
+	})
+	if err := lim.ResetShared(t0); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("lim.ResetShared() err: %v, want: %v", err, ErrNotSupported)
+	}
+}
+
+// slowEpochDatastore is an epochMemDatastore whose resets are stuck until
+// released.
+type slowEpochDatastore struct {
+	*epochMemDatastore
+	release chan struct{}
+}
+
+func (d *slowEpochDatastore) Reset(key string, start int64) (int64, error) {
+	<-d.release
+	return d.epochMemDatastore.Reset(key, start)
+}
+
+func TestLimiter_ResetShared_unlocked(t *testing.T) {
+	store := &slowEpochDatastore{epochMemDatastore: newEpochMemDatastore(), release: make(chan struct{})}
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+	})
+	lim.AllowN(t1, 5)
+
+	errC := make(chan error, 1)
+	go func() { errC <- lim.ResetShared(t2) }()
+
+	// The other calls go on while the reset is stuck.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lim.Count(t2)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("lim.Count() is stalled by lim.ResetShared()")
+	}
+
+	close(store.release)
+	if err := <-errC; err != nil {
+		t.Fatalf("lim.ResetShared() err: %v", err)
+	}
+	if got := lim.Count(t2); got != 0 {
+		t.Errorf("lim.Count(%v) = %d, want: 0", t2, got)
+	}
+}
@@ -0,0 +1,73 @@
+package slidingwindow
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SimClock is a simulated clock, which is useful for driving a limiter
+// through a scripted timeline in tests.
+type SimClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewSimClock creates a new simulated clock starting at time start.
+func NewSimClock(start time.Time) *SimClock {
+	return &SimClock{now: start}
+}
+
+// Now returns the current time of the clock.
+func (c *SimClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set sets the current time of the clock to t.
+func (c *SimClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the clock forward by d, and returns the new current time.
+func (c *SimClock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// SimStep is one step of a simulation, which expects that AllowN with n
+// events at the given offset from the start returns Allow.
+type SimStep struct {
+	At    time.Duration
+	N     int64
+	Allow bool
+}
+
+// Simulate runs the steps against lim in order. The offsets of the steps
+// are relative to the current time of clock, which is advanced step by step.
+// It returns an error describing all the steps whose decisions mismatch.
+func Simulate(lim *Limiter, clock *SimClock, steps []SimStep) error {
+	start := clock.Now()
+
+	var mismatches []string
+	for i, s := range steps {
+		now := start.Add(s.At)
+		clock.Set(now)
+
+		if ok := lim.AllowN(now, s.N); ok != s.Allow {
+			mismatches = append(mismatches, fmt.Sprintf(
+				"step %d: AllowN(+%v, %d) = %v, want: %v", i, s.At, s.N, ok, s.Allow))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("slidingwindow: simulation mismatched:\n%s", strings.Join(mismatches, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package slidingwindow
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSimClock(t *testing.T) {
+	c := NewSimClock(t0)
+	if got := c.Advance(d); !got.Equal(t1) {
+		t.Errorf("c.Advance(%v) = %v, want: %v", d, got, t1)
+	}
+	c.Set(t5)
+	if got := c.Now(); !got.Equal(t5) {
+		t.Errorf("c.Now() = %v, want: %v", got, t5)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	newLimiter := func() *Limiter {
+		lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+			return NewLocalWindow()
+		})
+		return lim
+	}
+
+	steps := []SimStep{
+		{0, 1, true},
+		{1 * d, 2, true},
+		{2 * d, 3, true},
+		{5 * d, 5, false},
+		{10 * d, 2, true},
+		{12 * d, 5, false},
+		{15 * d, 5, true},
+		{30 * d, 10, true},
+	}
+	if err := Simulate(newLimiter(), NewSimClock(t0), steps); err != nil {
+		t.Errorf("Simulate() err: %v", err)
+	}
+
+	steps[3].Allow = true
+	steps[5].Allow = true
+	clock := NewSimClock(t0)
+	err := Simulate(newLimiter(), clock, steps)
+	if err == nil || strings.Count(err.Error(), "\n") != 2 {
+		t.Errorf("Simulate() err: %v, want: 2 mismatches", err)
+	}
+	if got, want := clock.Now(), t0.Add(30*d); !got.Equal(want) {
+		t.Errorf("clock.Now() = %v, want: %v", got, want)
+	}
+}
@@ -1,7 +1,12 @@
 package slidingwindow
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -31,21 +36,87 @@ type StopFunc func()
 // the possible sync behaviour within it.
 type NewWindow func() (Window, StopFunc)
 
+// Option configures optional behaviours of a limiter.
+type Option func(*Limiter)
+
 type Limiter struct {
-	size  time.Duration
-	limit int64
+	// total is accessed atomically, and kept as the first field to
+	// guarantee the 64-bit alignment on 32-bit platforms.
+	total int64
+
+	size     time.Duration
+	limit    int64
+	aligner  Aligner
+	location *time.Location // The location of the calendar-aligned windows.
 
 	mu sync.Mutex
 
 	curr Window
 	prev Window
+
+	stopOnce sync.Once
+	stopCurr StopFunc
+	closed   bool // Whether the limiter has been stopped.
+
+	// The outstanding counts reserved by ReserveN.
+	reservedCurr int64
+	reservedPrev int64
+
+	denyHook  DenyHook
+	gapHook   GapHook
+	softLimit int64
+
+	maxCount int64
+	oversize OversizePolicy
+	bands    []priorityBand // Sorted by priority.
+
+	cooldown      time.Duration
+	cooldownUntil time.Time // The end of the cooldown, if any.
+	smoother      *smoother
+
+	weightFunc     WeightFunc
+	exact          bool // Whether to weight with rational arithmetic.
+	forwardLooking bool
+	demand         *demand
+	burst          *burstFilter
+
+	// The probabilistic tail starts at tailStart (as a fraction of the
+	// limit), which is disabled if zero.
+	tailStart float64
+	rand      *rand.Rand
+
+	dedupe *dedupe // Lazily created by AllowNOnce.
+
+	subs    []*subscriber
+	carried int64 // The count carried over by Resume, which is not reported.
+
+	paused   bool
+	pausedAt time.Time
+
+	// The states of Leak.
+	lastLeak      time.Time
+	leakRemainder float64
+
+	logger    Logger
+	firstSeen time.Time // The first time the limiter has been advanced to.
+	lastSeen  time.Time // The latest time the limiter has been advanced to.
+
+	// gen is incremented whenever the windows may have been changed,
+	// other than by advance.
+	gen        uint64
+	countCache *countCache
+	stats      *stats
 }
 
 // NewLimiter creates a new limiter, and returns a function to stop
 // the possible sync behaviour within the current window.
-func NewLimiter(size time.Duration, limit int64, newWindow NewWindow) (*Limiter, StopFunc) {
-	currWin, currStop := newWindow()
-
+//
+// The stop function is idempotent. Once it is called, the limiter keeps
+// working but only locally, i.e. no more sync behaviour will happen, and
+// the blocking methods (e.g. WaitN) fail with ErrClosed. Before stopping,
+// it does a final flush, whose error is discarded. Use StopWithResult
+// instead to check the error.
+func NewLimiter(size time.Duration, limit int64, newWindow NewWindow, opts ...Option) (*Limiter, StopFunc) {
 	// The previous window is static (i.e. no add changes will happen within it),
 	// so we always create it as an instance of LocalWindow.
 	//
@@ -54,14 +125,183 @@ func NewLimiter(size time.Duration, limit int64, newWindow NewWindow) (*Limiter,
 	// the current window.
 	prevWin, _ := NewLocalWindow()
 
-	lim := &Limiter{
-		size:  size,
-		limit: limit,
-		curr:  currWin,
-		prev:  prevWin,
+	lim := new(Limiter)
+	lim.init(size, limit, newWindow, prevWin, opts)
+	return lim, lim.stop
+}
+
+// init initializes all the states of lim with the given settings, and the
+// given empty previous window.
+func (lim *Limiter) init(size time.Duration, limit int64, newWindow NewWindow, prevWin Window, opts []Option) {
+	currWin, currStop := newWindow()
+
+	*lim = Limiter{
+		size:     size,
+		limit:    limit,
+		aligner:  sizeAligner{size: size},
+		curr:     currWin,
+		prev:     prevWin,
+		stopCurr: currStop,
+		logger:   nopLogger{},
+	}
+	for _, opt := range opts {
+		opt(lim)
+	}
+
+	if ca, ok := lim.aligner.(CalendarAligner); ok && lim.location != nil {
+		ca.Location = lim.location
+		lim.aligner = ca
+	}
+
+	if err := lim.weightFunc.validate(); err != nil {
+		lim.logger.Warnf("%v, which is clamped to [0, 1]", err)
 	}
 
-	return lim, currStop
+	if ls, ok := lim.curr.(loggerSetter); ok && lim.logger != (nopLogger{}) {
+		ls.setLogger(lim.logger)
+	}
+}
+
+// WithMaxCount sets an absolute ceiling on the raw count of the current
+// window, separate from the limit, which protects against pathological
+// spikes (e.g. of exempt events) inflating the window and taking a long
+// time to decay. The events beyond the cap are denied, or ignored if
+// exempt. A non-positive max disables it, which is the default.
+func WithMaxCount(max int64) Option {
+	return func(lim *Limiter) {
+		lim.maxCount = max
+	}
+}
+
+// WithCooldown sets a cooldown, which starts whenever the limit is breached
+// (i.e. events are denied for exceeding it), and during which all events
+// are denied by AllowN, regardless of how much the count decays. This is
+// useful for abuse mitigation. A non-positive d disables it, which is
+// the default.
+func WithCooldown(d time.Duration) Option {
+	return func(lim *Limiter) {
+		lim.cooldown = d
+	}
+}
+
+// DenyHook is called with the count observed at time now, when n events
+// are denied by AllowN, or admitted beyond the soft limit.
+type DenyHook func(now time.Time, n, count int64)
+
+// WithDenyHook sets the hook which is called whenever events are denied.
+// The hook is called synchronously, but outside the limiter's lock.
+func WithDenyHook(hook DenyHook) Option {
+	return func(lim *Limiter) {
+		lim.denyHook = hook
+	}
+}
+
+// WithSoftLimit sets a soft limit, beyond which events are still admitted
+// (until the limit is reached), but the deny hook is fired to give operators
+// a warning. A non-positive soft limit disables it, which is the default.
+func WithSoftLimit(softLimit int64) Option {
+	return func(lim *Limiter) {
+		lim.softLimit = softLimit
+	}
+}
+
+// WeightFunc returns the weight applied to the count of the previous window,
+// given the elapsed fraction of the current window in [0, 1].
+type WeightFunc func(fraction float64) float64
+
+// WithWeightFunc replaces the default linear weight, i.e. (1 - fraction),
+// with f, so that non-linear decay (e.g. quadratic or step) can be modeled.
+// f is expected to be non-increasing, and must return weights within [0, 1].
+//
+// New rejects f with ErrInvalidWeight if it returns an invalid weight for
+// any of a sample of fractions, while NewLimiter logs a warning instead,
+// and clamps the invalid weights to [0, 1].
+func WithWeightFunc(f WeightFunc) Option {
+	return func(lim *Limiter) {
+		lim.weightFunc = f
+	}
+}
+
+// weightSamples is the number of intervals into which [0, 1] is divided
+// to sample the fractions when validating a WeightFunc.
+const weightSamples = 100
+
+// validate checks that f returns weights within [0, 1] at the sampled
+// fractions. A nil f is always valid.
+func (f WeightFunc) validate() error {
+	if f == nil {
+		return nil
+	}
+	for i := 0; i <= weightSamples; i++ {
+		fraction := float64(i) / weightSamples
+		// Note that the comparison also rejects NaN.
+		if w := f(fraction); !(w >= 0 && w <= 1) {
+			return fmt.Errorf("%w: %v at fraction %v", ErrInvalidWeight, w, fraction)
+		}
+	}
+	return nil
+}
+
+// stop is the StopFunc of the limiter, which discards the error from
+// StopWithResult.
+func (lim *Limiter) stop() {
+	_ = lim.StopWithResult()
+}
+
+// StopWithResult does a final flush (see Flush), marks the limiter as
+// closed, and then stops the possible sync behaviour within the current
+// window. It returns the error from the final flush, if any, so that the
+// caller can log it or retry elsewhere. The limiter is stopped regardless.
+//
+// Like the stop function returned by NewLimiter, StopWithResult is
+// idempotent, and only the first call may return an error.
+func (lim *Limiter) StopWithResult() error {
+	var err error
+	lim.stopOnce.Do(func() {
+		err = lim.Flush(context.Background())
+
+		lim.mu.Lock()
+		lim.closed = true
+		lim.unsubscribeAll()
+		lim.mu.Unlock()
+
+		lim.stopCurr()
+	})
+	return err
+}
+
+// CurrentWindowForTest returns the current window of the limiter, as of
+// the latest call. It is intended for testing custom Window implementations
+// only, and the returned window must not be changed.
+func (lim *Limiter) CurrentWindowForTest() Window {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.curr
+}
+
+// PreviousWindowForTest returns the previous window of the limiter, as of
+// the latest call. It is intended for testing custom Window implementations
+// only, and the returned window must not be changed.
+func (lim *Limiter) PreviousWindowForTest() Window {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.prev
+}
+
+// isClosed reports whether the limiter has been stopped.
+func (lim *Limiter) isClosed() bool {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.closed
+}
+
+// sync triggers the possible sync behaviour within the current window,
+// unless the limiter has been stopped.
+func (lim *Limiter) sync(now time.Time) {
+	if !lim.closed {
+		defer lim.stats.End(statsSync, lim.stats.Begin())
+		lim.curr.Sync(now)
+	}
 }
 
 // Size returns the time duration of one window size. Note that the size
@@ -92,24 +332,178 @@ func (lim *Limiter) Allow() bool {
 
 // AllowN reports whether n events may happen at time now.
 func (lim *Limiter) AllowN(now time.Time, n int64) bool {
+	return lim.AllowNWithOptions(now, n, AllowOptions{})
+}
+
+// AllowNUpperBound is like AllowN, but makes the decision by the
+// conservative count, as returned by CountUpperBound.
+func (lim *Limiter) AllowNUpperBound(now time.Time, n int64) bool {
+	return lim.AllowNWithOptions(now, n, AllowOptions{UpperBound: true})
+}
+
+// AllowOptions overrides the behaviours of the limiter for a single call.
+type AllowOptions struct {
+	// Exempt makes the events always counted but never denied, which is
+	// useful for internal traffic. The deny hook is not fired for them.
+	Exempt bool
+
+	// UpperBound makes the decision by the conservative count, as
+	// returned by CountUpperBound.
+	UpperBound bool
+
+	// Priority is the priority of the events, see WithPriorityBands.
+	Priority int
+}
+
+// AllowNWithOptions is like AllowN, but with the given per-call options.
+func (lim *Limiter) AllowNWithOptions(now time.Time, n int64, opts AllowOptions) bool {
+	ok, count, notify := lim.allowN(now, n, opts, nil, nil)
+	if notify && lim.denyHook != nil {
+		// Fire the hook outside the lock, in case it calls the limiter.
+		lim.denyHook(now, n, count)
+	}
+	return ok
+}
+
+// AllowNAttributed is like AllowN, but also returns the start of the window
+// which the events are attributed to, e.g. for tagging the audit records to
+// be reconciled against the samples of Subscribe. With WithSmoothing, it is
+// the window in which the events are admitted, rather than settled.
+func (lim *Limiter) AllowNAttributed(now time.Time, n int64) (time.Time, bool) {
+	var start time.Time
+	ok, count, notify := lim.allowN(now, n, AllowOptions{}, nil, &start)
+	if notify && lim.denyHook != nil {
+		lim.denyHook(now, n, count)
+	}
+	return start, ok
+}
+
+// TryAddN atomically adds n events at time now, if the count will not exceed
+// the given limit, instead of the limiter's own limit. It reports whether
+// the events are added. The deny hook is never fired by TryAddN.
+func (lim *Limiter) TryAddN(now time.Time, n int64, limit int64) bool {
+	ok, _, _ := lim.allowN(now, n, AllowOptions{}, &limit, nil)
+	return ok
+}
+
+// IncrementAllowed is shorthand for TryAddN(time.Now(), 1, limit).
+func (lim *Limiter) IncrementAllowed(limit int64) bool {
+	return lim.TryAddN(time.Now(), 1, limit)
+}
+
+// allowN reports whether n events may happen at time now, along with
+// the count observed before them, and whether the deny hook should be fired.
+// If limit is nil, the limiter's own limit is used. If start is not nil, it
+// is set to the start of the window which the events are attributed to.
+func (lim *Limiter) allowN(now time.Time, n int64, opts AllowOptions, limit *int64, start *time.Time) (ok bool, count int64, notify bool) {
 	lim.mu.Lock()
 	defer lim.mu.Unlock()
+	defer lim.stats.End(statsAllowN, lim.stats.Begin())
 
-	lim.advance(now)
+	if limit == nil {
+		l := lim.priorityLimit(opts.Priority)
+		limit = &l
+	}
 
-	elapsed := now.Sub(lim.curr.Start())
-	weight := float64(lim.size-elapsed) / float64(lim.size)
-	count := int64(weight*float64(lim.prev.Count())) + lim.curr.Count()
+	lim.advance(now)
+	if start != nil {
+		*start = lim.curr.Start()
+	}
+	if opts.UpperBound {
+		count = lim.countUpperBound(now)
+	} else {
+		count = lim.count(now)
+	}
+	count += lim.smoother.Pending()
 
 	// Trigger the possible sync behaviour.
-	defer lim.curr.Sync(now)
+	defer lim.sync(now)
+	lim.gen++
 
-	if count+n > lim.limit {
-		return false
+	// All the attempts make up the demand, whether or not they are admitted.
+	lim.demand.Add(n)
+	admitted := n
+
+	if !opts.Exempt {
+		// Only count the events once they make up a burst large enough.
+		n = lim.burst.Filter(now, n)
+	}
+
+	if lim.maxCount > 0 {
+		// Never let the raw count of the current window exceed the cap.
+		if room := lim.maxCount - lim.curr.Count() - lim.smoother.Pending(); n > room {
+			if !opts.Exempt {
+				return false, count, true
+			}
+			// The exempt events beyond the cap are ignored.
+			n = room
+			if n < 0 {
+				n = 0
+			}
+		}
+	}
+
+	if opts.Exempt {
+		// The exempt events are never denied, thus the count may overflow.
+		if c := lim.curr.Count() + lim.smoother.Pending(); n > math.MaxInt64-c {
+			lim.logger.Warnf("slidingwindow: count saturated at %d", int64(math.MaxInt64))
+			n = math.MaxInt64 - c
+		}
+	} else {
+		if now.Before(lim.cooldownUntil) {
+			// All events are denied during the cooldown.
+			return false, count, true
+		}
+		// Note that the comparison is arranged to avoid overflow. The exempt
+		// events of a small burst are still denied once the limit is reached.
+		exceeded := n > *limit-count || (n < admitted && count >= *limit)
+		if exceeded && !lim.admitOversize(n, count, *limit) {
+			if lim.cooldown > 0 {
+				lim.cooldownUntil = now.Add(lim.cooldown)
+			}
+			return false, count, true
+		}
+		if !lim.admitTail(count, *limit) {
+			return false, count, true
+		}
+		// The events are admitted, but still worth a warning.
+		notify = lim.softLimit > 0 && count+n > lim.softLimit
+		lim.burst.Commit(now, admitted)
+	}
+	if lim.paused {
+		// The events are not counted during the pause.
+		return true, count, notify
 	}
+	atomic.AddInt64(&lim.total, admitted)
 
+	if lim.smoother != nil {
+		lim.smoother.Add(now, n)
+		return true, count, notify
+	}
 	lim.curr.AddCount(n)
-	return true
+	return true, count, notify
+}
+
+// Total returns the lifetime total of the admitted events since the limiter
+// was created, which never decays. Unlike Count, it never contends with
+// the other methods.
+func (lim *Limiter) Total() int64 {
+	return atomic.LoadInt64(&lim.total)
+}
+
+// IsWarm reports whether at least one window size has elapsed at time now
+// since the limiter was first used, before which the count tends to be
+// underestimated (e.g. the previous window of a freshly created limiter is
+// always empty), thus better treated as unreliable.
+//
+// Since the time is given by the callers, the limiter is considered created
+// at the first time passed to any of its methods.
+func (lim *Limiter) IsWarm(now time.Time) bool {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+	return now.Sub(lim.firstSeen) >= lim.size
 }
 
 // LimitReachedN reports whether the limit has been reached.
@@ -118,36 +512,305 @@ func (lim *Limiter) LimitReachedN(now time.Time, n int64) bool {
 	defer lim.mu.Unlock()
 
 	lim.advance(now)
-
-	elapsed := now.Sub(lim.curr.Start())
-	weight := float64(lim.size-elapsed) / float64(lim.size)
-	count := int64(weight*float64(lim.prev.Count())) + lim.curr.Count()
+	count := lim.count(now) + lim.smoother.Pending()
 
 	return count+n >= lim.limit
 }
 
+// Count returns the approximate count of events happened during the sliding
+// window ending at time now, excluding the outstanding reservations (i.e.
+// neither committed nor canceled), which is suitable for billing.
+//
+// See CountIncludingReservations for the count considered by the decisions.
+func (lim *Limiter) Count(now time.Time) int64 {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	defer lim.stats.End(statsCount, lim.stats.Begin())
+
+	if count, ok := lim.countCache.Get(now, lim.gen); ok {
+		return count
+	}
+
+	lim.advance(now)
+	start := lim.curr.Start()
+	end := lim.aligner.Next(start)
+	prevCount, currCount := lim.prev.Count()-lim.reservedPrev, lim.curr.Count()-lim.reservedCurr
+	count := lim.weighted(prevCount, currCount, start, end, now)
+	if lim.forwardLooking {
+		if projected, ok := projectedCount(currCount, start, end, now); ok {
+			count = projected
+		}
+	}
+	lim.countCache.Set(now, end, lim.gen, count)
+	return count
+}
+
+// CountUpperBound is like CountIncludingReservations, but returns the
+// conservative estimate, which is never less than Count: the weighted count
+// of the previous window is rounded up, and the inherited count is assumed
+// to be fully valid. This is suitable for never-exceed enforcement, see
+// AllowNUpperBound.
+func (lim *Limiter) CountUpperBound(now time.Time) int64 {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+	return lim.countUpperBound(now)
+}
+
+// CountIncludingReservations is like Count, but also includes the
+// outstanding reservations, which is the count considered by the decisions
+// to avoid over-admitting.
+func (lim *Limiter) CountIncludingReservations(now time.Time) int64 {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+	return lim.count(now)
+}
+
+// Remaining returns how many more events may happen at time now.
+func (lim *Limiter) Remaining(now time.Time) int64 {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+	return lim.remaining(lim.count(now))
+}
+
+// RemainingAt predicts how many events may happen at the given future time,
+// as if no more events happen from now on. The prediction is based on how
+// the count decays: the weight of the previous window drops linearly, and
+// the current window becomes the previous one at its end boundary.
+func (lim *Limiter) RemainingAt(future time.Time) int64 {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	return lim.remaining(lim.countAt(future))
+}
+
+// remaining returns the capacity left, given the weighted count.
+func (lim *Limiter) remaining(count int64) int64 {
+	r := lim.limit - count - lim.smoother.Pending()
+	if r < 0 {
+		return 0
+	}
+	return r
+}
+
+// ResetPrevious advances the limiter to time now, and then discards the count
+// of the previous window, while keeping the count of the current window.
+//
+// Unlike a full reset, which forgets all events (as if a new limiter was
+// created), ResetPrevious only forgives the history inherited from the
+// previous window. Right after the call, the sliding-window count equals
+// the count of the current window.
+func (lim *Limiter) ResetPrevious(now time.Time) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+	lim.prev.Reset(lim.prev.Start(), 0)
+	lim.gen++
+}
+
+// DrainCurrent advances the limiter to time now, and then atomically takes
+// the raw count of the current window and resets it to zero, leaving the
+// previous window untouched.
+//
+// Unlike Count, DrainCurrent is destructive: each event is reported by
+// exactly one call, which makes it suitable for metering.
+//
+// For a SyncWindow, the drain is synced to the central datastore like any
+// other change, thus the count drained also includes the events happened
+// on other nodes, as of the latest sync. In this case, DrainCurrent should
+// only be called on one of the nodes.
+func (lim *Limiter) DrainCurrent(now time.Time) int64 {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+
+	// Drain by a negative change rather than a reset, so that it will not
+	// be undone by the next sync.
+	count := lim.curr.Count()
+	lim.curr.AddCount(-count)
+	lim.gen++
+	return count
+}
+
+// SetCount advances the limiter to time now, and then sets the windows so
+// that the sliding-window count equals weighted, e.g. to seed a new limiter
+// by an observed count without replaying the events.
+//
+// The whole count is put into the current window, and the previous window
+// is discarded, as are the outstanding reservations. This is only an
+// approximation of the original history: the count will no longer decay
+// within the current window, but drop at once by becoming the previous one.
+func (lim *Limiter) SetCount(now time.Time, weighted int64) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+	lim.prev.Reset(lim.prev.Start(), 0)
+	// Seed by a change rather than a reset, so that a SyncWindow will sync
+	// it to the central datastore.
+	lim.curr.AddCount(weighted - lim.curr.Count())
+	lim.reservedPrev, lim.reservedCurr = 0, 0
+	lim.gen++
+}
+
+// count returns the weighted count of the sliding window ending at time now.
+// It must be called after advance.
+func (lim *Limiter) count(now time.Time) int64 {
+	start := lim.curr.Start()
+	end := lim.aligner.Next(start)
+	return lim.weighted(lim.prev.Count(), lim.curr.Count(), start, end, now)
+}
+
+// countUpperBound is like count, but rounds the weighted count of the
+// previous window up. It must be called after advance.
+func (lim *Limiter) countUpperBound(now time.Time) int64 {
+	start := lim.curr.Start()
+	end := lim.aligner.Next(start)
+	if c, ok := lim.exactWeighted(lim.prev.Count(), start, end, now, true); ok {
+		return c + lim.curr.Count()
+	}
+	return int64(math.Ceil(lim.weight(start, end, now)*float64(lim.prev.Count()))) + lim.curr.Count()
+}
+
+// countAt predicts the weighted count of the sliding window ending at time t,
+// which is not before the current window, as if no more events happen.
+// Unlike count, it never changes the windows.
+func (lim *Limiter) countAt(t time.Time) int64 {
+	prevCount, currCount := lim.prev.Count(), lim.curr.Count()
+	start := lim.curr.Start()
+	end := lim.aligner.Next(start)
+
+	if t.Before(start) {
+		t = start
+	}
+	if !t.Before(end) {
+		// The current window will have become the previous one.
+		next := lim.aligner.Next(end)
+		if !t.Before(next) {
+			// And then expired.
+			return 0
+		}
+		prevCount, currCount, start, end = currCount, 0, end, next
+	}
+
+	return lim.weighted(prevCount, currCount, start, end, t)
+}
+
+// weighted returns the weighted count of the sliding window ending at
+// time t, where the current window is [start, end).
+func (lim *Limiter) weighted(prevCount, currCount int64, start, end, t time.Time) int64 {
+	if c, ok := lim.exactWeighted(prevCount, start, end, t, false); ok {
+		return c + currCount
+	}
+	return int64(lim.weight(start, end, t)*float64(prevCount)) + currCount
+}
+
+// weight returns the weight of the previous window for the sliding window
+// ending at time t, where the current window is [start, end).
+func (lim *Limiter) weight(start, end, t time.Time) float64 {
+	if lim.paused {
+		// No decay happens during the pause.
+		t = lim.pausedAt
+	}
+	if lim.weightFunc == nil {
+		return linearWeight(start, end, t)
+	}
+
+	fraction := float64(t.Sub(start)) / float64(end.Sub(start))
+	weight := lim.weightFunc(fraction)
+	// Keep the weight within [0, 1], also for NaN.
+	if !(weight >= 0) {
+		weight = 0
+	} else if weight > 1 {
+		weight = 1
+	}
+	return weight
+}
+
+// linearWeight returns the default weight of the previous window for
+// the sliding window ending at time t, where the current window is
+// [start, end).
+func linearWeight(start, end, t time.Time) float64 {
+	return float64(end.Sub(t)) / float64(end.Sub(start))
+}
+
 // advance updates the current/previous windows resulting from the passage of time.
 func (lim *Limiter) advance(now time.Time) {
+	if lim.paused {
+		return
+	}
+
+	// Whether the limiter has ever been advanced, before which the windows
+	// are not anchored yet.
+	anchored := !lim.lastSeen.IsZero()
+	if !anchored {
+		lim.firstSeen = now
+	}
+
+	if now.Before(lim.lastSeen) {
+		lim.logger.Warnf("slidingwindow: clock moved backwards from %v to %v", lim.lastSeen, now)
+	} else {
+		lim.lastSeen = now
+	}
+
 	// Calculate the start boundary of the expected current-window.
-	newCurrStart := now.Truncate(lim.size)
+	newCurrStart := lim.aligner.Align(now)
 
-	diffSize := newCurrStart.Sub(lim.curr.Start()) / lim.size
-	if diffSize >= 1 {
+	if newCurrStart.After(lim.curr.Start()) {
 		// The current-window is at least one-window-size behind the expected one.
 
-		newPrevCount := int64(0)
-		if diffSize == 1 {
-			// The new previous-window will overlap with the old current-window,
-			// so it inherits the count.
+		// Whether the new previous-window will overlap with the old current-window.
+		adjacent := lim.aligner.Next(lim.curr.Start()).Equal(newCurrStart)
+		newPrevStart := lim.aligner.Align(newCurrStart.Add(-1))
+
+		// Apply the smoothed events which are due within the old
+		// current-window, before it moves along.
+		lim.smoother.Settle(lim.aligner.Next(lim.curr.Start()), lim.curr)
+
+		if lim.gapHook != nil && anchored && !adjacent {
+			from := lim.aligner.Next(lim.curr.Start())
+			lim.gapHook(countWindows(lim.aligner, from, newCurrStart), from, newCurrStart)
+		}
+
+		if anchored {
+			lim.publish()
+		}
+		lim.carried = 0
+
+		newPrevCount, newReservedPrev := int64(0), int64(0)
+		if adjacent {
+			// The new previous-window inherits the count.
 			//
 			// Note that the count here may be not accurate, since it is only a
 			// SNAPSHOT of the current-window's count, which in itself tends to
 			// be inaccurate due to the asynchronous nature of the sync behaviour.
 			newPrevCount = lim.curr.Count()
+			newReservedPrev = lim.reservedCurr
+		}
+		lim.prev.Reset(newPrevStart, newPrevCount)
+		if !adjacent {
+			// The smoothed events due within the new previous-window are
+			// applied to it, while those due earlier have expired.
+			lim.smoother.Skip(newPrevStart)
+			lim.smoother.Settle(newCurrStart, lim.prev)
 		}
-		lim.prev.Reset(newCurrStart.Add(-lim.size), newPrevCount)
 
 		// The new current-window always has zero count.
 		lim.curr.Reset(newCurrStart, 0)
+
+		// The outstanding reservations move along with the counts.
+		lim.reservedPrev, lim.reservedCurr = newReservedPrev, 0
+
+		lim.demand.Advance(newPrevStart, newCurrStart, adjacent)
 	}
+
+	// Apply the smoothed events which are due by now.
+	lim.smoother.Settle(now, lim.curr)
 }
@@ -1,7 +1,11 @@
 package slidingwindow
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -123,6 +127,87 @@ func TestLimiter_LocalWindow_LimitReachedN(t *testing.T) {
 	}
 }
 
+func TestLimiter_LocalWindow_ResetPrevious(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	// prev-window: [t0, t0 + 1s), count: 6
+	// curr-window: [t10, t10 + 1s), count: 2
+	lim.AllowN(t0, 6)
+	lim.AllowN(t10, 2)
+
+	if got, want := lim.Count(t12), int64(4*6/5+2); got != want {
+		t.Fatalf("lim.Count(%v) = %d, want: %d", t12, got, want)
+	}
+
+	lim.ResetPrevious(t12)
+
+	if got, want := lim.Count(t12), int64(2); got != want {
+		t.Errorf("lim.Count(%v) = %d, want: %d", t12, got, want)
+	}
+}
+
+func TestLimiter_LocalWindow_DrainCurrent(t *testing.T) {
+	lim, _ := NewLimiter(size, 1000, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		drained int64
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				lim.AllowN(t1, 1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				n := lim.DrainCurrent(t1)
+				mu.Lock()
+				drained += n
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	drained += lim.DrainCurrent(t1)
+
+	if drained != 500 {
+		t.Errorf("total drained = %d, want: 500", drained)
+	}
+	if got := lim.Count(t1); got != 0 {
+		t.Errorf("lim.Count(%v) = %d, want: 0", t1, got)
+	}
+}
+
+func TestLimiter_SyncWindow_DrainCurrent(t *testing.T) {
+	store := newMemDatastore()
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+	})
+
+	lim.AllowN(t1, 3)
+	if n := lim.DrainCurrent(t2); n != 3 {
+		t.Errorf("lim.DrainCurrent(%v) = %d, want: 3", t2, n)
+	}
+
+	// The drain is synced, instead of being undone by the next sync.
+	lim.AllowN(t3, 0)
+	if got := lim.Count(t3); got != 0 {
+		t.Errorf("lim.Count(%v) = %d, want: 0", t3, got)
+	}
+	if got, _ := store.Get("test", t0.UnixNano()); got != 0 {
+		t.Errorf("store.Get() = %d, want: 0", got)
+	}
+}
+
 type MemDatastore struct {
 	data map[string]int64
 	mu   sync.RWMutex
@@ -307,3 +392,560 @@ func TestLimiter_Nonblocking_SyncWindow_AllowN(t *testing.T) {
 		{t30, 10, true},
 	})
 }
+
+func TestErrors_Is(t *testing.T) {
+	sentinels := []error{
+		ErrInvalidSize,
+		ErrInvalidLimit,
+		ErrInvalidInterval,
+		ErrLimitExceeded,
+		ErrClosed,
+		ErrNotSupported,
+		ErrInvalidState,
+	}
+
+	for i, target := range sentinels {
+		wrapped := fmt.Errorf("context: %w", target)
+		for j, err := range sentinels {
+			got := errors.Is(wrapped, err)
+			if want := i == j; got != want {
+				t.Errorf("errors.Is(%v, %v) = %v, want: %v", wrapped, err, got, want)
+			}
+		}
+	}
+}
+
+func TestLimiter_Stop(t *testing.T) {
+	store := newMemDatastore()
+	lim, stop := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewNonblockingSynchronizer(store, 0))
+	})
+
+	stop()
+	stop() // no-op
+
+	// The limiter keeps working but only locally.
+	if ok := lim.AllowN(t1, 3); !ok {
+		t.Errorf("lim.AllowN(%v, 3) = false, want: true", t1)
+	}
+	if got := lim.Count(t1); got != 3 {
+		t.Errorf("lim.Count(%v) = %d, want: 3", t1, got)
+	}
+	if got, _ := store.Get("test", t0.UnixNano()); got != 0 {
+		t.Errorf("store.Get() = %d, want: 0", got)
+	}
+
+	if err := lim.WaitN(context.Background(), t1, 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("lim.WaitN() err: %v, want: %v", err, ErrClosed)
+	}
+}
+
+func TestLimiter_AllowNWithOptions(t *testing.T) {
+	var denied []int64
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithDenyHook(func(now time.Time, n, count int64) {
+		denied = append(denied, n)
+	}))
+
+	cases := []struct {
+		caseArg
+		opts AllowOptions
+	}{
+		{caseArg{t1, 8, true}, AllowOptions{}},
+		{caseArg{t2, 3, false}, AllowOptions{}},
+		{caseArg{t3, 4, true}, AllowOptions{Exempt: true}},
+		{caseArg{t4, 1, false}, AllowOptions{}},
+	}
+	for _, c := range cases {
+		ok := lim.AllowNWithOptions(c.t, c.n, c.opts)
+		if ok != c.ok {
+			t.Errorf("lim.AllowNWithOptions(%v, %v, %+v) = %v, want: %v",
+				c.t, c.n, c.opts, ok, c.ok)
+		}
+	}
+
+	// The exempt events are counted, but never fire the deny hook.
+	if got := lim.Count(t4); got != 12 {
+		t.Errorf("lim.Count(%v) = %d, want: 12", t4, got)
+	}
+	if len(denied) != 2 || denied[0] != 3 || denied[1] != 1 {
+		t.Errorf("denied = %v, want: [3 1]", denied)
+	}
+}
+
+func TestLimiter_WithSoftLimit(t *testing.T) {
+	var notified []int64
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithSoftLimit(6), WithDenyHook(func(now time.Time, n, count int64) {
+		notified = append(notified, count)
+	}))
+
+	cases := []caseArg{
+		{t1, 5, true},  // below the soft limit
+		{t2, 3, true},  // crossing the soft limit: admitted + hook
+		{t3, 2, true},  // reaching the limit: admitted + hook
+		{t4, 1, false}, // beyond the limit: denied + hook
+	}
+	for _, c := range cases {
+		ok := lim.AllowN(c.t, c.n)
+		if ok != c.ok {
+			t.Errorf("lim.AllowN(%v, %v) = %v, want: %v", c.t, c.n, ok, c.ok)
+		}
+	}
+
+	if want := []int64{5, 8, 10}; fmt.Sprint(notified) != fmt.Sprint(want) {
+		t.Errorf("notified = %v, want: %v", notified, want)
+	}
+}
+
+func TestLimiter_RemainingAt(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	// prev-window: [t0, t0 + 1s), count: 5
+	// curr-window: [t10, t10 + 1s), count: 4
+	lim.AllowN(t0, 5)
+	lim.AllowN(t10, 4)
+
+	if got := lim.Remaining(t12); got != 2 {
+		t.Errorf("lim.Remaining(%v) = %d, want: 2", t12, got)
+	}
+
+	cases := []struct {
+		future    time.Time
+		remaining int64
+	}{
+		{t12, 2},            // (10 - 4/5*5 - 4)
+		{t15, 4},            // (10 - 1/2*5 - 4)
+		{t18, 5},            // (10 - 1/5*5 - 4)
+		{t0.Add(20 * d), 6}, // crossing the window: (10 - 4)
+		{t0.Add(25 * d), 8}, // (10 - 1/2*4)
+		{t30, 10},           // a clean window
+		{t30.Add(size), 10},
+	}
+	prev := int64(0)
+	for _, c := range cases {
+		got := lim.RemainingAt(c.future)
+		if got != c.remaining {
+			t.Errorf("lim.RemainingAt(%v) = %d, want: %d", c.future, got, c.remaining)
+		}
+		if got < prev {
+			t.Errorf("lim.RemainingAt(%v) = %d, decreases from %d", c.future, got, prev)
+		}
+		prev = got
+	}
+}
+
+func TestLimiter_WithWeightFunc(t *testing.T) {
+	cases := []struct {
+		name string
+		f    WeightFunc
+		want []int64
+	}{
+		{
+			"linear",
+			func(fraction float64) float64 { return 1 - fraction },
+			[]int64{100, 75, 50, 25},
+		},
+		{
+			"square-root",
+			func(fraction float64) float64 { return 1 - math.Sqrt(fraction) },
+			[]int64{100, 50, 29, 13},
+		},
+		{
+			"out-of-range",
+			func(fraction float64) float64 { return 2 - 4*fraction },
+			[]int64{100, 100, 0, 0},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lim, _ := NewLimiter(size, 1000, func() (Window, StopFunc) {
+				return NewLocalWindow()
+			}, WithWeightFunc(c.f))
+
+			// prev-window: [t0, t0 + 1s), count: 100
+			// curr-window: [t10, t10 + 1s), count: 0
+			lim.AllowN(t0, 100)
+
+			for i, want := range c.want {
+				now := t10.Add(time.Duration(i) * size / 4)
+				if got := lim.Count(now); got != want {
+					t.Errorf("lim.Count(%v) = %d, want: %d", now, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter_WithWeightFunc_Invalid(t *testing.T) {
+	logger := new(recordLogger)
+	NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithLogger(logger), WithWeightFunc(func(fraction float64) float64 {
+		return 2 - 4*fraction
+	}))
+
+	want := "slidingwindow: invalid weight"
+	if warns := logger.Warns(); len(warns) != 1 || !strings.HasPrefix(warns[0], want) {
+		t.Errorf("warns = %q, want prefix: %q", warns, want)
+	}
+}
+
+func TestLimiter_WithWeightFunc_WaitDelay(t *testing.T) {
+	// The weight drops to zero at once in the middle of the window.
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithWeightFunc(func(fraction float64) float64 {
+		if fraction < 0.5 {
+			return 1
+		}
+		return 0
+	}))
+
+	// prev-window: [t0, t0 + 1s), count: 8
+	// curr-window: [t10, t10 + 1s), count: 0
+	lim.AllowN(t0, 8)
+
+	if delay, ok := lim.delayN(t12, 5); !ok || delay != 3*d {
+		t.Errorf("lim.delayN(%v, 5) = (%v, %v), want: (%v, true)", t12, delay, ok, 3*d)
+	}
+}
+
+// recordWindow is a custom window which records how it is driven.
+type recordWindow struct {
+	LocalWindow
+	resets []string
+	syncs  int
+}
+
+func (w *recordWindow) Reset(s time.Time, c int64) {
+	w.resets = append(w.resets, fmt.Sprintf("%v:%d", s.Sub(t0), c))
+	w.LocalWindow.Reset(s, c)
+}
+
+func (w *recordWindow) Sync(now time.Time) {
+	w.syncs++
+}
+
+func TestLimiter_WindowsForTest(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return &recordWindow{}, func() {}
+	})
+
+	lim.AllowN(t1, 3)
+	lim.AllowN(t12, 2) // rollover
+
+	curr := lim.CurrentWindowForTest().(*recordWindow)
+	if want := "[0s:0 1s:0]"; fmt.Sprint(curr.resets) != want {
+		t.Errorf("curr.resets = %v, want: %v", curr.resets, want)
+	}
+	if curr.syncs != 2 {
+		t.Errorf("curr.syncs = %d, want: 2", curr.syncs)
+	}
+	if got := curr.Count(); got != 2 {
+		t.Errorf("curr.Count() = %d, want: 2", got)
+	}
+
+	prev := lim.PreviousWindowForTest()
+	if !prev.Start().Equal(t0) || prev.Count() != 3 {
+		t.Errorf("prev = (%v, %d), want: (%v, 3)", prev.Start(), prev.Count(), t0)
+	}
+}
+
+func TestLimiter_TryAddN(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	cases := []caseArg{
+		{t1, 3, true},
+		{t2, 2, true},
+		{t3, 1, false}, // count will be (3 + 2 + 1) = 6, exceeding 5
+	}
+	for _, c := range cases {
+		if ok := lim.TryAddN(c.t, c.n, 5); ok != c.ok {
+			t.Errorf("lim.TryAddN(%v, %v, 5) = %v, want: %v", c.t, c.n, ok, c.ok)
+		}
+	}
+}
+
+func TestLimiter_IncrementAllowed(t *testing.T) {
+	lim, _ := NewLimiter(time.Hour, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	for i := 0; i < 3; i++ {
+		if ok := lim.IncrementAllowed(3); !ok {
+			t.Errorf("#%d: lim.IncrementAllowed(3) = false, want: true", i)
+		}
+	}
+	if ok := lim.IncrementAllowed(3); ok {
+		t.Errorf("lim.IncrementAllowed(3) = true, want: false")
+	}
+}
+
+func TestLimiter_Total(t *testing.T) {
+	lim, _ := NewLimiter(size, 10, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	cases := []struct {
+		at        time.Duration
+		n         int64
+		wantCount int64
+		wantTotal int64
+	}{
+		{0, 6, 6, 6},
+		{size / 2, 6, 6, 6}, // denied
+		{size / 2, 4, 10, 10},
+		{size + size/2, 3, 8, 13},
+		{3 * size, 1, 1, 14},
+	}
+	for _, c := range cases {
+		now := t0.Add(c.at)
+		lim.AllowN(now, c.n)
+		if count := lim.Count(now); count != c.wantCount {
+			t.Errorf("lim.Count(%v) = %d, want: %d", now, count, c.wantCount)
+		}
+		if total := lim.Total(); total != c.wantTotal {
+			t.Errorf("lim.Total() at %v = %d, want: %d", now, total, c.wantTotal)
+		}
+	}
+}
+
+func TestLimiter_SetCount(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	lim.AllowN(t0, 4)
+	lim.AllowN(t15, 3)
+
+	lim.SetCount(t15, 7)
+	if got := lim.Count(t15); got != 7 {
+		t.Errorf("lim.Count(%v) = %d, want: 7", t15, got)
+	}
+	// The count stays until the current window becomes the previous one.
+	if got := lim.Count(t18); got != 7 {
+		t.Errorf("lim.Count(%v) = %d, want: 7", t18, got)
+	}
+	if got, want := lim.Count(t30.Add(-size/2)), int64(7/2); got != want {
+		t.Errorf("lim.Count() = %d, want: %d", got, want)
+	}
+}
+
+func TestLimiter_SyncWindow_SetCount(t *testing.T) {
+	store := newMemDatastore()
+	newLimiter := func() *Limiter {
+		lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+			return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+		})
+		return lim
+	}
+
+	lim1 := newLimiter()
+	lim1.AllowN(t1, 2)
+	lim1.SetCount(t2, 7)
+
+	// The seeded count is synced, and thus shared by other nodes.
+	lim1.AllowN(t3, 0)
+	if got := lim1.Count(t3); got != 7 {
+		t.Errorf("lim1.Count(%v) = %d, want: 7", t3, got)
+	}
+	lim2 := newLimiter()
+	lim2.AllowN(t3, 0)
+	if got := lim2.Count(t3); got != 7 {
+		t.Errorf("lim2.Count(%v) = %d, want: 7", t3, got)
+	}
+}
+
+func TestLimiter_AllowN_allocs(t *testing.T) {
+	lim, _ := NewLimiter(size, math.MaxInt64, newLocalWindow)
+
+	now := t0
+	allocs := testing.AllocsPerRun(1000, func() {
+		// Cross the window boundaries from time to time.
+		now = now.Add(d / 4)
+		lim.AllowN(now, 1)
+	})
+	if allocs != 0 {
+		t.Errorf("allocs per AllowN = %v, want: 0", allocs)
+	}
+}
+
+func BenchmarkLimiter_AllowN(b *testing.B) {
+	lim, _ := NewLimiter(size, math.MaxInt64, newLocalWindow)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lim.AllowN(t0, 1)
+	}
+}
+
+func BenchmarkLimiter_AllowN_Parallel(b *testing.B) {
+	lim, _ := NewLimiter(size, math.MaxInt64, newLocalWindow)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			lim.AllowN(t0, 1)
+		}
+	})
+}
+
+func TestLimiter_IsWarm(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, newLocalWindow)
+
+	cases := []struct {
+		now  time.Time
+		want bool
+	}{
+		{t5, false},
+		{t10, false},
+		{t14, false},
+		{t15, true},
+		{t30, true},
+	}
+	for _, c := range cases {
+		if got := lim.IsWarm(c.now); got != c.want {
+			t.Errorf("lim.IsWarm(%v) = %v, want: %v", c.now, got, c.want)
+		}
+	}
+}
+
+func TestLimiter_WithCooldown(t *testing.T) {
+	lim, _ := NewLimiter(size, 10, newLocalWindow, WithCooldown(size))
+
+	cases := []struct {
+		now  time.Time
+		n    int64
+		want bool
+	}{
+		{t0, 10, true},
+		{t5, 1, false}, // breached, the cooldown lasts until t15
+		{t6, 1, false},
+		// The count has decayed to 10 * 6/10, but it is still cooling down.
+		{t14, 1, false},
+		{t15, 1, true},
+	}
+	for _, c := range cases {
+		if got := lim.AllowN(c.now, c.n); got != c.want {
+			t.Errorf("lim.AllowN(%v, %d) = %v, want: %v", c.now, c.n, got, c.want)
+		}
+	}
+}
+
+func TestLimiter_CountUpperBound(t *testing.T) {
+	lim, _ := NewLimiter(size, 10, newLocalWindow)
+	lim.AllowN(t0, 3)
+	lim.AllowN(t10, 2)
+
+	// 3 * 7/10 = 2.1, which is rounded down by Count, but up by the upper bound.
+	if got := lim.Count(t13); got != 2+2 {
+		t.Errorf("lim.Count(%v) = %d, want: %d", t13, got, 2+2)
+	}
+	if got := lim.CountUpperBound(t13); got != 3+2 {
+		t.Errorf("lim.CountUpperBound(%v) = %d, want: %d", t13, got, 3+2)
+	}
+
+	for i := 13; i <= 30; i++ {
+		now := t0.Add(time.Duration(i) * d)
+		count, upper := lim.Count(now), lim.CountUpperBound(now)
+		if upper < count {
+			t.Errorf("at %v: upper bound %d < count %d", now, upper, count)
+		}
+	}
+
+	// The same events are admitted by the normal count, but denied by
+	// the upper bound.
+	newLimiter := func() *Limiter {
+		lim, _ := NewLimiter(size, 10, newLocalWindow)
+		lim.AllowN(t0, 3)
+		lim.AllowN(t10, 2)
+		return lim
+	}
+	if newLimiter().AllowNUpperBound(t13, 6) {
+		t.Errorf("AllowNUpperBound(%v, 6) = true, want: false", t13)
+	}
+	if !newLimiter().AllowN(t13, 6) {
+		t.Errorf("AllowN(%v, 6) = false, want: true", t13)
+	}
+}
+
+func TestLimiter_WithMaxCount(t *testing.T) {
+	lim, _ := NewLimiter(size, 10, newLocalWindow, WithMaxCount(15))
+
+	exempt := AllowOptions{Exempt: true}
+	cases := []struct {
+		n       int64
+		opts    AllowOptions
+		want    bool
+		wantRaw int64
+	}{
+		{8, AllowOptions{}, true, 8},
+		{5, exempt, true, 13},
+		{5, exempt, true, 15}, // only 2 counted
+		{5, exempt, true, 15}, // ignored
+		{1, AllowOptions{}, false, 15},
+	}
+	for _, c := range cases {
+		if got := lim.AllowNWithOptions(t0, c.n, c.opts); got != c.want {
+			t.Errorf("lim.AllowNWithOptions(%d, %+v) = %v, want: %v", c.n, c.opts, got, c.want)
+		}
+		if raw := lim.curr.Count(); raw != c.wantRaw {
+			t.Errorf("lim.curr.Count() = %d, want: %d", raw, c.wantRaw)
+		}
+	}
+
+	// Even under a higher limit, the events beyond the cap are denied.
+	lim2, _ := NewLimiter(size, 100, newLocalWindow, WithMaxCount(15))
+	lim2.AllowN(t0, 10)
+	if lim2.AllowN(t0, 6) {
+		t.Errorf("lim2.AllowN(6) = true, want: false")
+	}
+	if raw := lim2.curr.Count(); raw != 10 {
+		t.Errorf("lim2.curr.Count() = %d, want: 10", raw)
+	}
+}
+
+func TestLimiter_AllowNAttributed(t *testing.T) {
+	lim, _ := NewLimiter(size, 100, newLocalWindow)
+	ch := lim.Subscribe()
+
+	cases := []struct {
+		now       time.Time
+		n         int64
+		wantStart time.Time
+	}{
+		{t0, 1, t0},
+		{t0.Add(size - time.Nanosecond), 2, t0},
+		{t0.Add(size), 3, t0.Add(size)},
+		{t0.Add(2*size - time.Nanosecond), 4, t0.Add(size)},
+		{t0.Add(2 * size), 5, t0.Add(2 * size)},
+	}
+	totals := make(map[time.Time]int64)
+	for _, c := range cases {
+		start, ok := lim.AllowNAttributed(c.now, c.n)
+		if !ok {
+			t.Fatalf("lim.AllowNAttributed(%v, %d) ok = false, want: true", c.now, c.n)
+		}
+		if !start.Equal(c.wantStart) {
+			t.Errorf("lim.AllowNAttributed(%v, %d) start = %v, want: %v", c.now, c.n, start, c.wantStart)
+		}
+		totals[start] += c.n
+	}
+
+	// The attributed events reconcile with the per-window totals.
+	for i := 0; i < 2; i++ {
+		s := <-ch
+		if s.Count != totals[s.Start] {
+			t.Errorf("sample of %v: Count = %d, want: %d", s.Start, s.Count, totals[s.Start])
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// WithSmoothing spreads the count of the events admitted by AllowN evenly
+// across the duration d after they happen, rather than applying it at once.
+// This reduces the spikiness of Count right after a burst.
+//
+// Admission decisions still take the whole count of the pending events into
+// consideration, so smoothing never lets more events happen than the limit.
+// A non-positive d disables smoothing, which is the default.
+func WithSmoothing(d time.Duration) Option {
+	return func(lim *Limiter) {
+		if d <= 0 {
+			lim.smoother = nil
+			return
+		}
+		lim.smoother = &smoother{d: d}
+	}
+}
+
+// smoother gradually applies the count of admitted events to a window.
+//
+// All methods are safe to call on a nil smoother, which does nothing.
+type smoother struct {
+	d time.Duration
+
+	adds    []smoothedAdd
+	pending int64 // Total count not applied yet.
+}
+
+type smoothedAdd struct {
+	at      time.Time
+	n       int64
+	applied int64
+}
+
+// Add records n events happened at time now.
+func (s *smoother) Add(now time.Time, n int64) {
+	s.adds = append(s.adds, smoothedAdd{at: now, n: n})
+	s.pending += n
+}
+
+// Pending returns the count which has not been applied yet.
+func (s *smoother) Pending() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.pending
+}
+
+// Settle applies the count which is due by time now to w.
+func (s *smoother) Settle(now time.Time, w Window) {
+	s.settle(now, w.AddCount)
+}
+
+// Skip discards the count which is due by time now, e.g. since it falls
+// out of the sliding window.
+func (s *smoother) Skip(now time.Time) {
+	s.settle(now, func(int64) {})
+}
+
+// settle passes the count which is due by time now to apply.
+func (s *smoother) settle(now time.Time, apply func(n int64)) {
+	if s == nil {
+		return
+	}
+
+	kept := s.adds[:0]
+	for _, a := range s.adds {
+		due := a.n
+		if elapsed := now.Sub(a.at); elapsed < s.d {
+			due = int64(float64(a.n) * float64(elapsed) / float64(s.d))
+		}
+		if due > a.applied {
+			apply(due - a.applied)
+			s.pending -= due - a.applied
+			a.applied = due
+		}
+		if a.applied < a.n {
+			kept = append(kept, a)
+		}
+	}
+	s.adds = kept
+}
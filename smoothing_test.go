@@ -0,0 +1,62 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_WithSmoothing(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithSmoothing(4*d))
+
+	if ok := lim.AllowN(t0, 8); !ok {
+		t.Fatalf("lim.AllowN(%v, 8) = false, want: true", t0)
+	}
+
+	// The pending count is still considered by admission decisions.
+	if ok := lim.AllowN(t0, 3); ok {
+		t.Errorf("lim.AllowN(%v, 3) = true, want: false", t0)
+	}
+
+	// The effect of the burst on Count ramps up, rather than steps.
+	cases := []struct {
+		now   int
+		count int64
+	}{
+		{0, 0},
+		{1, 2},
+		{2, 4},
+		{3, 6},
+		{4, 8},
+		{5, 8},
+	}
+	for _, c := range cases {
+		now := t0.Add(d * time.Duration(c.now))
+		if got := lim.Count(now); got != c.count {
+			t.Errorf("lim.Count(%v) = %d, want: %d", now, got, c.count)
+		}
+	}
+}
+
+func TestLimiter_WithSmoothing_idle(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithSmoothing(4*size))
+
+	// 2 events are due within each window in [t0, t0 + 4s).
+	lim.AllowN(t0, 8)
+
+	// prev-window: [t0 + 1s, t0 + 2s), count: 2
+	// curr-window: [t0 + 2s, t0 + 3s), count: 0
+	now := t0.Add(2 * size)
+	if got := lim.Count(now); got != 2 {
+		t.Errorf("lim.Count(%v) = %d, want: 2", now, got)
+	}
+
+	// The events due several windows ago have expired.
+	now = t0.Add(30 * size)
+	if got := lim.Count(now); got != 0 {
+		t.Errorf("lim.Count(%v) = %d, want: 0", now, got)
+	}
+}
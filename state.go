@@ -0,0 +1,100 @@
+package slidingwindow
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// stateVersion is the version of the format used by ExportState.
+const stateVersion = 1
+
+// ExportState advances the limiter to time now, and then encodes the counts
+// of both windows into a compact binary form which can be transferred to
+// a peer and then loaded by ImportState.
+//
+// The window boundaries are intentionally left out, since the clocks of
+// two nodes may be skewed. The importer re-anchors the windows at its own
+// time instead.
+func (lim *Limiter) ExportState(now time.Time) []byte {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+
+	buf := make([]byte, 1+2*binary.MaxVarintLen64)
+	buf[0] = stateVersion
+	i := 1
+	i += binary.PutVarint(buf[i:], lim.prev.Count())
+	i += binary.PutVarint(buf[i:], lim.curr.Count())
+	return buf[:i]
+}
+
+// ImportState advances the limiter to time now, and then seeds its windows
+// with the counts encoded by ExportState. The exported current window is
+// mapped onto the window containing now, and the exported previous window
+// onto the one before it.
+//
+// On a SyncWindow, only the part of the imported count beyond the shared
+// one is synced to the central datastore. Thus the counts exported from
+// a node sharing the same datastore are not counted twice, while those
+// from elsewhere are synced as usual. Note that the shared count is fetched
+// without holding the limiter's lock, and the imported count is dropped if
+// the window moves along in the meantime.
+func (lim *Limiter) ImportState(data []byte, now time.Time) error {
+	if len(data) == 0 || data[0] != stateVersion {
+		return ErrInvalidState
+	}
+
+	data = data[1:]
+	prevCount, n := binary.Varint(data)
+	if n <= 0 {
+		return ErrInvalidState
+	}
+	data = data[n:]
+	currCount, n := binary.Varint(data)
+	if n <= 0 || n != len(data) {
+		return ErrInvalidState
+	}
+	if prevCount < 0 || currCount < 0 {
+		return ErrInvalidState
+	}
+
+	lim.mu.Lock()
+	lim.advance(now)
+	lim.prev.Reset(lim.prev.Start(), prevCount)
+	lim.gen++
+
+	s, ok := lim.curr.(windowSeeder)
+	if !ok {
+		// Seed by a change rather than a reset, so that a custom window
+		// which syncs will sync it.
+		lim.curr.AddCount(currCount - lim.curr.Count())
+		lim.mu.Unlock()
+		return nil
+	}
+	lim.mu.Unlock()
+
+	return s.seedShared(currCount, lim.locked)
+}
+
+// windowSeeder is implemented by windows which sync their counts, and thus
+// need to tell a seeded count apart from the counted events.
+type windowSeeder interface {
+	// seedLocal sets the count of the window without syncing it.
+	seedLocal(count int64)
+
+	// seedShared seeds the window with count, of which only the part beyond
+	// the shared count is synced. The state of the window is only accessed
+	// within locked, which runs f with the window's lock held.
+	seedShared(count int64, locked func(f func())) error
+}
+
+// seedLocal sets the count of the current window without syncing it to
+// the central datastore, if any.
+func (lim *Limiter) seedLocal(count int64) {
+	if s, ok := lim.curr.(windowSeeder); ok {
+		s.seedLocal(count)
+		return
+	}
+	lim.curr.Reset(lim.curr.Start(), count)
+}
@@ -0,0 +1,123 @@
+package slidingwindow
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLimiter_ExportState_ImportState(t *testing.T) {
+	newWindow := func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}
+
+	// prev-window: [t0, t0 + 1s), count: 6
+	// curr-window: [t10, t10 + 1s), count: 2
+	lim1, _ := NewLimiter(size, limit, newWindow)
+	lim1.AllowN(t0, 6)
+	lim1.AllowN(t10, 2)
+	want := lim1.Count(t12)
+
+	// The peer's clock is skewed by 3s, the windows are re-anchored.
+	lim2, _ := NewLimiter(size, limit, newWindow)
+	if err := lim2.ImportState(lim1.ExportState(t12), t30.Add(2*d)); err != nil {
+		t.Fatalf("lim2.ImportState() err: %v", err)
+	}
+	if got := lim2.Count(t30.Add(2 * d)); got != want {
+		t.Errorf("lim2.Count() = %d, want: %d", got, want)
+	}
+}
+
+func TestLimiter_ImportState_Invalid(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	for _, data := range [][]byte{
+		nil,
+		{0},
+		{stateVersion},
+		{stateVersion, 2},
+		{stateVersion, 2, 4, 6},
+		{stateVersion, 1, 4}, // prev: -1
+		{stateVersion, 2, 3}, // curr: -2
+	} {
+		if err := lim.ImportState(data, t0); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("lim.ImportState(%v) err: %v, want: %v", data, err, ErrInvalidState)
+		}
+	}
+}
+
+func TestLimiter_ExportState_idle(t *testing.T) {
+	newWindow := func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}
+
+	lim1, _ := NewLimiter(size, limit, newWindow)
+	lim1.AllowN(t0, 10)
+
+	// After being idle for more than one window, all the counts expire.
+	lim2, _ := NewLimiter(size, limit, newWindow)
+	if err := lim2.ImportState(lim1.ExportState(t30), t30); err != nil {
+		t.Fatalf("lim2.ImportState() err: %v", err)
+	}
+	if got := lim2.Count(t30); got != 0 {
+		t.Errorf("lim2.Count() = %d, want: 0", got)
+	}
+	if !lim2.AllowN(t30, limit) {
+		t.Errorf("lim2.AllowN() = false, want: true")
+	}
+}
+
+func TestLimiter_ImportState_syncWindow(t *testing.T) {
+	src, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+	src.AllowN(t1, 4)
+
+	store := newMemDatastore()
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+	})
+	if err := lim.ImportState(src.ExportState(t2), t2); err != nil {
+		t.Fatalf("lim.ImportState() err: %v", err)
+	}
+
+	// The imported count is synced, instead of being wiped by the next sync.
+	lim.AllowN(t3, 0)
+	if got := lim.Count(t3); got != 4 {
+		t.Errorf("lim.Count() = %d, want: 4", got)
+	}
+	if got, _ := store.Get("test", t0.UnixNano()); got != 4 {
+		t.Errorf("store.Get() = %d, want: 4", got)
+	}
+}
+
+func TestLimiter_ImportState_sharedDatastore(t *testing.T) {
+	store := newMemDatastore()
+	newSyncLimiter := func() *Limiter {
+		lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+			return NewSyncWindow("test", NewBlockingSynchronizer(store, 0))
+		})
+		return lim
+	}
+
+	src := newSyncLimiter()
+	src.AllowN(t1, 4)
+
+	// The imported count has been synced by src, thus is not synced again.
+	lim := newSyncLimiter()
+	if err := lim.ImportState(src.ExportState(t2), t2); err != nil {
+		t.Fatalf("lim.ImportState() err: %v", err)
+	}
+	if got, _ := store.Get("test", t0.UnixNano()); got != 4 {
+		t.Errorf("store.Get() = %d, want: 4", got)
+	}
+
+	lim.AllowN(t3, 1)
+	if got := lim.Count(t3); got != 5 {
+		t.Errorf("lim.Count(%v) = %d, want: 5", t3, got)
+	}
+	if got, _ := store.Get("test", t0.UnixNano()); got != 5 {
+		t.Errorf("store.Get() = %d, want: 5", got)
+	}
+}
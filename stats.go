@@ -0,0 +1,107 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// WithStats makes the limiter record the latencies of its own operations,
+// which can be queried by Stats. The latencies are measured while holding
+// the limiter's lock, thus they indicate how long the lock is held.
+func WithStats() Option {
+	return func(lim *Limiter) {
+		lim.stats = new(stats)
+	}
+}
+
+// OpStats is the latency distribution of an operation.
+type OpStats struct {
+	Calls int64
+	Min   time.Duration
+	Max   time.Duration
+	Avg   time.Duration
+}
+
+// LimiterStats is the latency distributions of the limiter's operations.
+type LimiterStats struct {
+	AllowN OpStats // Including the sync triggered.
+	Count  OpStats
+	Sync   OpStats
+}
+
+// Stats returns the latency distributions of the limiter's operations.
+// It returns zero stats if the limiter is not created with WithStats.
+func (lim *Limiter) Stats() LimiterStats {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if lim.stats == nil {
+		return LimiterStats{}
+	}
+	return LimiterStats{
+		AllowN: lim.stats.ops[statsAllowN].Stats(),
+		Count:  lim.stats.ops[statsCount].Stats(),
+		Sync:   lim.stats.ops[statsSync].Stats(),
+	}
+}
+
+type statsOp int
+
+const (
+	statsAllowN statsOp = iota
+	statsCount
+	statsSync
+	numStatsOps
+)
+
+// stats records the latencies of the limiter's operations. It is guarded
+// by the limiter's lock.
+//
+// All methods are safe to call on a nil stats, which does nothing.
+type stats struct {
+	ops [numStatsOps]opStats
+}
+
+// Begin returns the time at which an operation begins.
+func (s *stats) Begin() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// End records the latency of op, which began at begin.
+func (s *stats) End(op statsOp, begin time.Time) {
+	if s == nil {
+		return
+	}
+	s.ops[op].Add(time.Since(begin))
+}
+
+type opStats struct {
+	calls    int64
+	min, max time.Duration
+	total    time.Duration
+}
+
+func (o *opStats) Add(latency time.Duration) {
+	if o.calls == 0 || latency < o.min {
+		o.min = latency
+	}
+	if latency > o.max {
+		o.max = latency
+	}
+	o.calls++
+	o.total += latency
+}
+
+func (o *opStats) Stats() OpStats {
+	if o.calls == 0 {
+		return OpStats{}
+	}
+	return OpStats{
+		Calls: o.calls,
+		Min:   o.min,
+		Max:   o.max,
+		Avg:   o.total / time.Duration(o.calls),
+	}
+}
@@ -0,0 +1,31 @@
+package slidingwindow
+
+import (
+	"testing"
+)
+
+func TestLimiter_Stats(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	}, WithStats())
+
+	if s := lim.Stats(); s != (LimiterStats{}) {
+		t.Errorf("lim.Stats() = %+v, want: zero", s)
+	}
+
+	for i := 0; i < 10; i++ {
+		lim.AllowN(t0, 1)
+		lim.Count(t0)
+	}
+
+	s := lim.Stats()
+	ops := map[string]OpStats{"AllowN": s.AllowN, "Count": s.Count, "Sync": s.Sync}
+	for name, o := range ops {
+		if o.Calls != 10 {
+			t.Errorf("lim.Stats().%s.Calls = %d, want: 10", name, o.Calls)
+		}
+		if o.Max <= 0 || o.Min > o.Avg || o.Avg > o.Max {
+			t.Errorf("lim.Stats().%s = %+v, want: Min <= Avg <= Max and Max > 0", name, o)
+		}
+	}
+}
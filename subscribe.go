@@ -0,0 +1,127 @@
+package slidingwindow
+
+import (
+	"time"
+)
+
+// WindowSample is the total of a completed window.
+type WindowSample struct {
+	Start time.Time
+	End   time.Time
+	Count int64
+}
+
+// SubscribeOption configures a subscription of Subscribe.
+type SubscribeOption func(*subscriber)
+
+// WithSampleBuffer sets the number of the samples buffered for a slow
+// consumer, which defaults to 16.
+func WithSampleBuffer(n int) SubscribeOption {
+	return func(s *subscriber) {
+		s.buffer = n
+	}
+}
+
+// WithDropOldest makes the subscription drop the oldest buffered sample,
+// instead of the newest one, when the buffer is full.
+func WithDropOldest() SubscribeOption {
+	return func(s *subscriber) {
+		s.dropOldest = true
+	}
+}
+
+type subscriber struct {
+	ch         chan WindowSample
+	buffer     int
+	dropOldest bool
+}
+
+// send sends sample without blocking, dropping one sample if the buffer
+// is full.
+func (s *subscriber) send(sample WindowSample) {
+	select {
+	case s.ch <- sample:
+		return
+	default:
+	}
+	if !s.dropOldest {
+		return
+	}
+
+	select {
+	case <-s.ch:
+	default:
+	}
+	select {
+	case s.ch <- sample:
+	default:
+	}
+}
+
+// Subscribe returns a channel, on which a sample is emitted each time
+// a window completes, i.e. when the limiter rolls over to a later window.
+// The windows skipped with zero activity (see WithGapHook) are not emitted.
+//
+// The samples are sent without blocking, thus a slow consumer never stalls
+// the limiter, but may miss samples once its buffer is full. The channel is
+// closed by Unsubscribe, or when the limiter is stopped.
+func (lim *Limiter) Subscribe(opts ...SubscribeOption) <-chan WindowSample {
+	s := &subscriber{buffer: 16}
+	for _, o := range opts {
+		o(s)
+	}
+	s.ch = make(chan WindowSample, s.buffer)
+
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if lim.closed {
+		close(s.ch)
+		return s.ch
+	}
+	lim.subs = append(lim.subs, s)
+	return s.ch
+}
+
+// Unsubscribe cancels the subscription of ch, and closes it.
+func (lim *Limiter) Unsubscribe(ch <-chan WindowSample) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	for i, s := range lim.subs {
+		if s.ch == ch {
+			close(s.ch)
+			lim.subs = append(lim.subs[:i], lim.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// publish emits the sample of the current window, which is about to be
+// completed, to all the subscribers.
+func (lim *Limiter) publish() {
+	if len(lim.subs) == 0 {
+		return
+	}
+
+	start := lim.curr.Start()
+	sample := WindowSample{
+		Start: start,
+		End:   lim.aligner.Next(start),
+		Count: lim.curr.Count() - lim.reservedCurr - lim.carried,
+	}
+	if sample.Count < 0 {
+		sample.Count = 0
+	}
+	for _, s := range lim.subs {
+		s.send(sample)
+	}
+}
+
+// unsubscribeAll cancels all the subscriptions.
+func (lim *Limiter) unsubscribeAll() {
+	for _, s := range lim.subs {
+		close(s.ch)
+	}
+	lim.subs = nil
+}
@@ -0,0 +1,90 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Subscribe(t *testing.T) {
+	lim, stop := NewLimiter(size, limit, newLocalWindow)
+	ch := lim.Subscribe()
+
+	// Complete 3 windows, with i+1 events in the i-th one.
+	const n = 3
+	for i := 0; i < n; i++ {
+		lim.AllowN(t0.Add(time.Duration(i)*size), int64(i+1))
+	}
+	lim.AllowN(t0.Add(n*size), 0)
+
+	for i := 0; i < n; i++ {
+		start := t0.Add(time.Duration(i) * size)
+		want := WindowSample{Start: start, End: start.Add(size), Count: int64(i + 1)}
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Errorf("sample #%d = %+v, want: %+v", i, got, want)
+			}
+		default:
+			t.Fatalf("sample #%d = none, want: %+v", i, want)
+		}
+	}
+	select {
+	case got := <-ch:
+		t.Errorf("extra sample = %+v, want: none", got)
+	default:
+	}
+
+	stop()
+	if _, ok := <-ch; ok {
+		t.Errorf("channel after stop = open, want: closed")
+	}
+}
+
+func TestLimiter_Subscribe_slowConsumer(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, newLocalWindow)
+	newest := lim.Subscribe(WithSampleBuffer(2))
+	oldest := lim.Subscribe(WithSampleBuffer(2), WithDropOldest())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i <= 100; i++ {
+			lim.AllowN(t0.Add(time.Duration(i)*size), 1)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("AllowN stalled by the slow consumers")
+	}
+
+	cases := []struct {
+		name string
+		ch   <-chan WindowSample
+		want []time.Time
+	}{
+		{"drop newest", newest, []time.Time{t0, t0.Add(size)}},
+		{"drop oldest", oldest, []time.Time{t0.Add(98 * size), t0.Add(99 * size)}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for i, want := range c.want {
+				if got := <-c.ch; !got.Start.Equal(want) {
+					t.Errorf("sample #%d.Start = %v, want: %v", i, got.Start, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter_Unsubscribe(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, newLocalWindow)
+	ch := lim.Subscribe()
+	lim.AllowN(t0, 1)
+	lim.Unsubscribe(ch)
+
+	lim.AllowN(t0.Add(size), 1)
+	if got, ok := <-ch; ok {
+		t.Errorf("sample after unsubscribing = %+v, want: none", got)
+	}
+}
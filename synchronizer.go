@@ -1,56 +1,203 @@
 package slidingwindow
 
 import (
-	"log"
+	"context"
+	"sync"
 	"time"
 )
 
 // Datastore represents the central datastore.
 type Datastore interface {
 	// Add adds delta to the count of the window represented
-	// by start, and returns the new count.
+	// by start, and returns the new count. Note that delta may be
+	// negative, e.g. when the reserved events are refunded.
 	Add(key string, start, delta int64) (int64, error)
 
 	// Get returns the count of the window represented by start.
 	Get(key string, start int64) (int64, error)
 }
 
+// EpochDatastore is a central datastore which also supports coordinated
+// resets across nodes. Each reset increments the reset epoch of the key,
+// and the changes made by a node before it observes the new epoch are
+// dropped, instead of re-inflating the count after the reset.
+type EpochDatastore interface {
+	Datastore
+
+	// AddInEpoch is like Add, but only adds delta if the reset epoch of
+	// key equals epoch, or epoch is negative (i.e. unknown). It returns
+	// the new count along with the current epoch.
+	AddInEpoch(key string, start, delta, epoch int64) (count, currEpoch int64, err error)
+
+	// Reset clears the count of the window represented by start, and
+	// increments the reset epoch of key, which is returned.
+	Reset(key string, start int64) (epoch int64, err error)
+}
+
+// CASDatastore is a central datastore which also supports an atomic
+// compare-and-add, for detecting the conflicting concurrent updates from
+// other nodes instead of blindly incrementing the count.
+type CASDatastore interface {
+	Datastore
+
+	// CompareAndAdd adds delta to the count of the window represented by
+	// start, only if the count equals expected. It returns the new count
+	// if swapped, or the current count otherwise.
+	CompareAndAdd(key string, start, expected, delta int64) (count int64, swapped bool, err error)
+}
+
+// maxCASRetries is the maximum number of retries on a conflict, before
+// falling back to a plain Add.
+const maxCASRetries = 3
+
+// SyncFlusher is implemented by synchronizers which can also sync
+// synchronously on demand.
+type SyncFlusher interface {
+	// Flush sends a synchronization request, and then waits for the
+	// response, regardless of the sync interval. It returns the context's
+	// error once the context is done, in which case the response may still
+	// be handled later.
+	//
+	// Unlike Sync, Flush is called without the window's lock held, which
+	// allows the other calls to go on during the exchange with the central
+	// datastore. Instead, MakeFunc and HandleFunc take the lock by themselves,
+	// and may be called from another goroutine.
+	Flush(context.Context, MakeFunc, HandleFunc) error
+}
+
 // syncHelper is a helper that will be leveraged by both BlockingSynchronizer
 // and NonblockingSynchronizer.
 type syncHelper struct {
 	store        Datastore
 	syncInterval time.Duration
 
-	inProgress bool // Whether the synchronization is in progress.
+	// mu guards the states below, which are also accessed by Flush without
+	// the window's lock held.
+	mu         sync.Mutex
+	inProgress bool          // Whether the synchronization is in progress.
+	idle       chan struct{} // Closed once the synchronization in progress ends.
 	lastSynced time.Time
+	prevSynced time.Time // The value of lastSynced before the latest TryBegin.
+
+	logger Logger
 }
 
 func newSyncHelper(store Datastore, syncInterval time.Duration) *syncHelper {
-	return &syncHelper{store: store, syncInterval: syncInterval}
+	return &syncHelper{store: store, syncInterval: syncInterval, logger: stdLogger{}}
 }
 
-// IsTimeUp returns whether it's time to sync data to the central datastore.
-func (h *syncHelper) IsTimeUp(now time.Time) bool {
-	return !h.inProgress && now.Sub(h.lastSynced) >= h.syncInterval
+// TryBegin begins a synchronization at time now, and returns true, if it's
+// time to sync data to the central datastore.
+func (h *syncHelper) TryBegin(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.inProgress || now.Sub(h.lastSynced) < h.syncInterval {
+		return false
+	}
+	h.begin()
+	h.prevSynced, h.lastSynced = h.lastSynced, now
+	return true
 }
 
 func (h *syncHelper) InProgress() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	return h.inProgress
 }
 
-func (h *syncHelper) Begin(now time.Time) {
+func (h *syncHelper) End() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.end()
+}
+
+// Abort ends the synchronization begun by TryBegin, which has not been
+// done after all, so that the next one is not delayed by the sync interval.
+func (h *syncHelper) Abort() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastSynced = h.prevSynced
+	h.end()
+}
+
+// end must be called with h.mu held.
+func (h *syncHelper) end() {
+	if h.inProgress {
+		h.inProgress = false
+		close(h.idle)
+	}
+}
+
+// begin must be called with h.mu held.
+func (h *syncHelper) begin() {
 	h.inProgress = true
-	h.lastSynced = now
+	h.idle = make(chan struct{})
 }
 
-func (h *syncHelper) End() {
-	h.inProgress = false
+// Flush waits for the synchronization in progress, if any, to end, and then
+// syncs in another goroutine, while waiting for it until ctx is done. While
+// waiting for the synchronization in progress, the responses received from
+// respC (if not nil) are handled as well.
+func (h *syncHelper) Flush(ctx context.Context, respC <-chan SyncResponse, makeReq MakeFunc, handleResp HandleFunc) error {
+	for {
+		h.mu.Lock()
+		if !h.inProgress {
+			h.begin()
+			h.mu.Unlock()
+			break
+		}
+		idle := h.idle
+		h.mu.Unlock()
+
+		select {
+		case resp := <-respC:
+			handleResp(resp)
+			h.End()
+		case <-idle:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		h.End()
+		return err
+	}
+
+	req := makeReq()
+	errC := make(chan error, 1)
+	go func() {
+		// Even if ctx is done, the response must be handled, lest the
+		// changes be sent again by the next synchronization.
+		defer h.End()
+
+		resp, err := h.Sync(req)
+		if err == nil {
+			handleResp(resp)
+		}
+		errC <- err
+	}()
+
+	select {
+	case err := <-errC:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (h *syncHelper) Sync(req SyncRequest) (resp SyncResponse, err error) {
-	var newCount int64
-
-	if req.Changes > 0 {
+	var newCount, epoch int64
+
+	if es, ok := h.store.(EpochDatastore); ok {
+		// The changes are dropped if the epoch has been changed by a reset.
+		newCount, epoch, err = es.AddInEpoch(req.Key, req.Start, req.Changes, req.Epoch)
+	} else if cs, ok := h.store.(CASDatastore); ok && req.Changes != 0 {
+		newCount, err = h.compareAndAdd(cs, req)
+	} else if req.Changes != 0 {
+		// The negative changes (i.e. refunds) must be synced as well.
 		newCount, err = h.store.Add(req.Key, req.Start, req.Changes)
 	} else {
 		newCount, err = h.store.Get(req.Key, req.Start)
@@ -65,9 +212,47 @@ func (h *syncHelper) Sync(req SyncRequest) (resp SyncResponse, err error) {
 		Start:        req.Start,
 		Changes:      req.Changes,
 		OtherChanges: newCount - req.Count,
+		Epoch:        epoch,
 	}, nil
 }
 
+// compareAndAdd adds the changes of req to the shared count, expecting the
+// count last seen by the window, and retries with the current count on
+// a conflict.
+func (h *syncHelper) compareAndAdd(cs CASDatastore, req SyncRequest) (int64, error) {
+	// The shared count last seen, excluding the local changes.
+	expected := req.Count - req.Changes
+	for i := 0; i <= maxCASRetries; i++ {
+		count, swapped, err := cs.CompareAndAdd(req.Key, req.Start, expected, req.Changes)
+		if err != nil {
+			return 0, err
+		}
+		if swapped {
+			return count, nil
+		}
+		h.logger.Debugf("slidingwindow: conflict on %s@%d: expected %d, got %d", req.Key, req.Start, expected, count)
+		expected = count
+	}
+	// Too much contention, so just increment the count.
+	return cs.Add(req.Key, req.Start, req.Changes)
+}
+
+// ResetShared resets the count of the window represented by start in the
+// central datastore, across all nodes.
+func (h *syncHelper) ResetShared(key string, start int64) (int64, error) {
+	es, ok := h.store.(EpochDatastore)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return es.Reset(key, start)
+}
+
+// Get returns the count of the window represented by start in the central
+// datastore.
+func (h *syncHelper) Get(key string, start int64) (int64, error) {
+	return h.store.Get(key, start)
+}
+
 // BlockingSynchronizer does synchronization in a blocking mode and consumes
 // no extra goroutine.
 //
@@ -83,6 +268,18 @@ func NewBlockingSynchronizer(store Datastore, syncInterval time.Duration) *Block
 	}
 }
 
+func (s *BlockingSynchronizer) setLogger(l Logger) {
+	s.helper.logger = l
+}
+
+func (s *BlockingSynchronizer) resetShared(key string, start int64) (int64, error) {
+	return s.helper.ResetShared(key, start)
+}
+
+func (s *BlockingSynchronizer) getShared(key string, start int64) (int64, error) {
+	return s.helper.Get(key, start)
+}
+
 func (s *BlockingSynchronizer) Start() {}
 
 func (s *BlockingSynchronizer) Stop() {}
@@ -90,12 +287,10 @@ func (s *BlockingSynchronizer) Stop() {}
 // Sync sends the window's count to the central datastore, and then update
 // the window's count according to the response from the datastore.
 func (s *BlockingSynchronizer) Sync(now time.Time, makeReq MakeFunc, handleResp HandleFunc) {
-	if s.helper.IsTimeUp(now) {
-		s.helper.Begin(now)
-
+	if s.helper.TryBegin(now) {
 		resp, err := s.helper.Sync(makeReq())
 		if err != nil {
-			log.Printf("err: %v\n", err)
+			s.helper.logger.Warnf("slidingwindow: sync failed: %v", err)
 		}
 
 		handleResp(resp)
@@ -103,6 +298,13 @@ func (s *BlockingSynchronizer) Sync(now time.Time, makeReq MakeFunc, handleResp
 	}
 }
 
+// Flush sends the window's count to the central datastore, and then update
+// the window's count according to the response from the datastore,
+// regardless of the sync interval.
+func (s *BlockingSynchronizer) Flush(ctx context.Context, makeReq MakeFunc, handleResp HandleFunc) error {
+	return s.helper.Flush(ctx, nil, makeReq, handleResp)
+}
+
 // NonblockingSynchronizer does synchronization in a non-blocking mode. To achieve
 // this, it needs to spawn a goroutine to exchange data with the central datastore.
 //
@@ -127,6 +329,18 @@ func NewNonblockingSynchronizer(store Datastore, syncInterval time.Duration) *No
 	}
 }
 
+func (s *NonblockingSynchronizer) setLogger(l Logger) {
+	s.helper.logger = l
+}
+
+func (s *NonblockingSynchronizer) resetShared(key string, start int64) (int64, error) {
+	return s.helper.ResetShared(key, start)
+}
+
+func (s *NonblockingSynchronizer) getShared(key string, start int64) (int64, error) {
+	return s.helper.Get(key, start)
+}
+
 func (s *NonblockingSynchronizer) Start() {
 	go s.syncLoop()
 }
@@ -144,7 +358,7 @@ func (s *NonblockingSynchronizer) syncLoop() {
 		case req := <-s.reqC:
 			resp, err := s.helper.Sync(req)
 			if err != nil {
-				log.Printf("err: %v\n", err)
+				s.helper.logger.Warnf("slidingwindow: sync failed: %v", err)
 			}
 
 			select {
@@ -166,13 +380,13 @@ exit:
 // Since the exchange with the datastore is always slower than the execution of Sync,
 // usually Sync must be called at least twice to update the window's count finally.
 func (s *NonblockingSynchronizer) Sync(now time.Time, makeReq MakeFunc, handleResp HandleFunc) {
-	if s.helper.IsTimeUp(now) {
+	if s.helper.TryBegin(now) {
 		// Just try to sync. If this fails, we assume the previous synchronization
 		// is still ongoing, and we wait for the next time.
 		select {
 		case s.reqC <- makeReq():
-			s.helper.Begin(now)
 		default:
+			s.helper.Abort()
 		}
 	}
 
@@ -186,3 +400,13 @@ func (s *NonblockingSynchronizer) Sync(now time.Time, makeReq MakeFunc, handleRe
 		}
 	}
 }
+
+// Flush waits for the latest synchronization, if any, to complete. Then it
+// sends the window's count to the central datastore, and update the window's
+// count according to the response from the datastore, both without the sync
+// goroutine.
+func (s *NonblockingSynchronizer) Flush(ctx context.Context, makeReq MakeFunc, handleResp HandleFunc) error {
+	// Once no synchronization is in progress, the sync goroutine is idle,
+	// and it's safe to access the datastore.
+	return s.helper.Flush(ctx, s.respC, makeReq, handleResp)
+}
@@ -0,0 +1,29 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNonblockingSynchronizer_Sync_busy(t *testing.T) {
+	// The sync loop is not started, thus the requests can never be sent.
+	s := NewNonblockingSynchronizer(newMemDatastore(), time.Hour)
+
+	calls := 0
+	makeReq := func() SyncRequest {
+		calls++
+		return SyncRequest{Key: "test"}
+	}
+	handleResp := func(SyncResponse) {}
+
+	// The failed attempt is retried on the next call, instead of one sync
+	// interval later.
+	s.Sync(t0, makeReq, handleResp)
+	s.Sync(t1, makeReq, handleResp)
+	if calls != 2 {
+		t.Errorf("calls = %d, want: 2", calls)
+	}
+	if s.helper.InProgress() {
+		t.Errorf("s.helper.InProgress() = true, want: false")
+	}
+}
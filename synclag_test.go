@@ -0,0 +1,69 @@
+package slidingwindow
+
+import (
+	"testing"
+	"time"
+)
+
+// slowDatastore is a MemDatastore whose adds block until released.
+type slowDatastore struct {
+	*MemDatastore
+	release chan struct{}
+}
+
+func (d *slowDatastore) Add(key string, start, delta int64) (int64, error) {
+	<-d.release
+	return d.MemDatastore.Add(key, start, delta)
+}
+
+func TestSyncWindow_WithSyncLagHook(t *testing.T) {
+	size := time.Hour
+	base := time.Now().Truncate(size)
+	at := func(d time.Duration) time.Time { return base.Add(d) }
+
+	store := &slowDatastore{MemDatastore: newMemDatastore(), release: make(chan struct{})}
+	var lags []time.Duration
+	lim, stop := NewLimiter(size, 100, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewNonblockingSynchronizer(store, 0),
+			WithSyncLagHook(500*time.Millisecond, func(lag time.Duration) {
+				lags = append(lags, lag)
+			}))
+	})
+	defer stop()
+
+	// The first sync is stuck in the slow datastore.
+	lim.AllowN(at(0), 1)
+	lim.AllowN(at(100*time.Millisecond), 1)
+	if len(lags) != 0 {
+		t.Fatalf("lags = %v, want: none", lags)
+	}
+
+	lim.AllowN(at(600*time.Millisecond), 1)
+	lim.AllowN(at(700*time.Millisecond), 1) // fires once per stall
+	if want := []time.Duration{600 * time.Millisecond}; len(lags) != 1 || lags[0] != want[0] {
+		t.Fatalf("lags = %v, want: %v", lags, want)
+	}
+
+	close(store.release)
+}
+
+func TestSyncWindow_WithSyncLagHook_healthy(t *testing.T) {
+	size := time.Hour
+	base := time.Now().Truncate(size)
+
+	fired := false
+	lim, stop := NewLimiter(size, 1000, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(newMemDatastore(), 200*time.Millisecond),
+			WithSyncLagHook(500*time.Millisecond, func(lag time.Duration) {
+				fired = true
+			}))
+	})
+	defer stop()
+
+	for i := 0; i < 100; i++ {
+		lim.AllowN(base.Add(time.Duration(i)*50*time.Millisecond), 1)
+	}
+	if fired {
+		t.Errorf("fired = true, want: false")
+	}
+}
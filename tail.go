@@ -0,0 +1,53 @@
+package slidingwindow
+
+import (
+	"math/rand"
+)
+
+// WithProbabilisticTail makes admission probabilistic once the count exceeds
+// startFrac (in (0, 1)) of the limit, for graceful degradation rather than
+// a hard cliff. Beyond that point, events are admitted with a probability
+// proportional to the remaining capacity, which drops linearly from 100%
+// at startFrac of the limit to 0% at the limit.
+//
+// A startFrac out of (0, 1) disables the tail, which is the default.
+func WithProbabilisticTail(startFrac float64) Option {
+	return func(lim *Limiter) {
+		if !(startFrac > 0 && startFrac < 1) {
+			startFrac = 0
+		}
+		lim.tailStart = startFrac
+	}
+}
+
+// WithRandSource sets the source of randomness used by the probabilistic
+// tail, which is useful for deterministic tests. By default, the global
+// source of math/rand is used.
+func WithRandSource(src rand.Source) Option {
+	return func(lim *Limiter) {
+		lim.rand = rand.New(src)
+	}
+}
+
+// admitTail reports whether the events are admitted by the probabilistic
+// tail, given the count observed before them. It must be called with the
+// lock held.
+func (lim *Limiter) admitTail(count, limit int64) bool {
+	if lim.tailStart == 0 {
+		return true
+	}
+
+	start := lim.tailStart * float64(limit)
+	if float64(count) <= start {
+		return true
+	}
+	p := (float64(limit) - float64(count)) / (float64(limit) - start)
+
+	var r float64
+	if lim.rand != nil {
+		r = lim.rand.Float64()
+	} else {
+		r = rand.Float64()
+	}
+	return r < p
+}
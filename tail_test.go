@@ -0,0 +1,46 @@
+package slidingwindow
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestLimiter_WithProbabilisticTail(t *testing.T) {
+	const (
+		limit  = 1000
+		trials = 2000
+	)
+
+	// admitRate returns the admission rate at the given count.
+	admitRate := func(count int64) float64 {
+		lim, _ := NewLimiter(time.Hour, limit, func() (Window, StopFunc) {
+			return NewLocalWindow()
+		}, WithProbabilisticTail(0.5), WithRandSource(rand.NewSource(1)))
+		lim.AllowNWithOptions(t0, count, AllowOptions{Exempt: true})
+
+		admitted := 0
+		for i := 0; i < trials; i++ {
+			if lim.AllowN(t0, 0) {
+				admitted++
+			}
+		}
+		return float64(admitted) / trials
+	}
+
+	cases := []struct {
+		count    int64
+		min, max float64
+	}{
+		{100, 1, 1},       // well below the limit
+		{500, 1, 1},       // the start of the tail
+		{750, 0.45, 0.55}, // halfway in the tail
+		{990, 0, 0.05},    // near the limit
+		{1000, 0, 0},      // at the limit
+	}
+	for _, c := range cases {
+		if rate := admitRate(c.count); rate < c.min || rate > c.max {
+			t.Errorf("admission rate at count %d = %v, want: [%v, %v]", c.count, rate, c.min, c.max)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package slidingwindow
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// TransportOption configures optional behaviours of a transport.
+type TransportOption func(*Transport)
+
+// WithKeyFunc sets a function which decides the key of the limiter for each
+// outbound request. By default, the key is the host of the request URL.
+func WithKeyFunc(f func(*http.Request) string) TransportOption {
+	return func(t *Transport) {
+		t.keyFunc = f
+	}
+}
+
+// WithFailFast makes the transport fail the requests exceeding the limit
+// at once, with an error wrapping ErrLimitExceeded, instead of waiting
+// until they are permitted.
+func WithFailFast() TransportOption {
+	return func(t *Transport) {
+		t.failFast = true
+	}
+}
+
+// Transport is an http.RoundTripper which limits the outbound requests per
+// key (e.g. per host) before sending them by the base RoundTripper. This is
+// useful to cap how fast a client hits a third-party API.
+type Transport struct {
+	base     http.RoundTripper
+	limiters *LimiterMap
+	keyFunc  func(*http.Request) string
+	failFast bool
+}
+
+// NewTransport creates a transport, which limits the requests by limiters
+// and sends them by base. If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper, limiters *LimiterMap, opts ...TransportOption) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{
+		base:     base,
+		limiters: limiters,
+		keyFunc: func(req *http.Request) string {
+			return req.URL.Host
+		},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper. By default, it blocks until the
+// request is permitted, or the request's context is done.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := t.keyFunc(req)
+	lim := t.limiters.Get(key)
+
+	var err error
+	if t.failFast {
+		if !lim.Allow() {
+			err = fmt.Errorf("%w: request to %q", ErrLimitExceeded, key)
+		}
+	} else {
+		err = lim.Wait(req.Context())
+	}
+
+	if err != nil {
+		// RoundTrip must always close the body, even on errors.
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,88 @@
+package slidingwindow
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	newClientWithOptions := func(size time.Duration, limOpts []Option, opts ...TransportOption) *http.Client {
+		limiters := NewLimiterMap(func(key string) (*Limiter, StopFunc) {
+			return NewLimiter(size, 2, func() (Window, StopFunc) {
+				return NewLocalWindow()
+			}, limOpts...)
+		})
+		return &http.Client{Transport: NewTransport(nil, limiters, opts...)}
+	}
+	newClient := func(size time.Duration, opts ...TransportOption) *http.Client {
+		return newClientWithOptions(size, nil, opts...)
+	}
+
+	get := func(c *http.Client) error {
+		resp, err := c.Get(server.URL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	t.Run("block", func(t *testing.T) {
+		size := 100 * time.Millisecond
+		c := newClient(size)
+
+		// Start at a window boundary, so that the count of the first two
+		// requests does not decay before the next window.
+		time.Sleep(time.Until(time.Now().Truncate(size).Add(size)))
+
+		begin := time.Now()
+		for i := 0; i < 3; i++ {
+			if err := get(c); err != nil {
+				t.Fatalf("request %d: err: %v", i, err)
+			}
+		}
+		// The third request must wait for the next window at least.
+		if elapsed := time.Since(begin); elapsed < size/2 {
+			t.Errorf("requests took %v, want: >= %v", elapsed, size/2)
+		}
+	})
+
+	t.Run("block during cooldown", func(t *testing.T) {
+		size := 100 * time.Millisecond
+		cooldown := 3 * size
+		c := newClientWithOptions(size, []Option{WithCooldown(cooldown)})
+		c.Timeout = time.Second
+
+		time.Sleep(time.Until(time.Now().Truncate(size).Add(size)))
+
+		begin := time.Now()
+		for i := 0; i < 3; i++ {
+			if err := get(c); err != nil {
+				t.Fatalf("request %d: err: %v", i, err)
+			}
+		}
+		// The third request breaches the limit, and then must wait for
+		// the cooldown to end, instead of spinning until the deadline.
+		if elapsed := time.Since(begin); elapsed < cooldown || elapsed > 2*cooldown {
+			t.Errorf("requests took %v, want: about %v", elapsed, cooldown)
+		}
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		c := newClient(time.Hour, WithFailFast())
+
+		for i := 0; i < 2; i++ {
+			if err := get(c); err != nil {
+				t.Fatalf("request %d: err: %v", i, err)
+			}
+		}
+		if err := get(c); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("request 2: err: %v, want: %v", err, ErrLimitExceeded)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package slidingwindow
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Wait is shorthand for WaitN(ctx, time.Now(), 1).
+func (lim *Limiter) Wait(ctx context.Context) error {
+	return lim.WaitN(ctx, time.Now(), 1)
+}
+
+// WaitN blocks until n events are permitted to happen, starting from time
+// now. It returns an error if n exceeds the limit, the limiter has been
+// stopped, or the context is canceled. Like x/time/rate, if the required
+// delay exceeds the context deadline, it returns at once with an error
+// wrapping context.DeadlineExceeded, instead of waiting in vain.
+//
+// WaitN, together with Allow, AllowN, Wait and Reserve, makes Limiter a near
+// drop-in replacement for golang.org/x/time/rate.Limiter. Note that the
+// semantics of a sliding window differ from a token bucket:
+//
+//   - There is no separate burst size. Up to limit events may happen at
+//     once, as long as the sliding-window count permits.
+//   - Capacity is not refilled at a constant rate. It is released as the
+//     previous window's weight decays, and at once when a window with
+//     little traffic becomes the previous one.
+//   - The delay is calculated from the count observed at the time of the
+//     call. For a SyncWindow, events happened on other nodes may extend
+//     the actual delay, in which case WaitN simply waits again.
+func (lim *Limiter) WaitN(ctx context.Context, now time.Time, n int64) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if lim.isClosed() {
+			return ErrClosed
+		}
+
+		if lim.AllowN(now, n) {
+			return nil
+		}
+
+		delay, ok := lim.delayN(now, n)
+		if !ok {
+			return fmt.Errorf("%w: WaitN(n=%d) exceeds limit %d", ErrLimitExceeded, n, lim.Limit())
+		}
+		if deadline, ok := ctx.Deadline(); ok && delay > deadline.Sub(now) {
+			return fmt.Errorf("%w: WaitN(n=%d) would exceed the deadline", context.DeadlineExceeded, n)
+		}
+		if delay <= 0 {
+			// The estimation may be slightly earlier than the exact
+			// moment due to the float arithmetic.
+			delay = time.Nanosecond
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		now = now.Add(delay)
+	}
+}
+
+// delayN returns the duration to wait, starting from time now, before n
+// events are permitted to happen, and false if they will never be.
+func (lim *Limiter) delayN(now time.Time, n int64) (time.Duration, bool) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+	return lim.delay(now, n)
+}
+
+// delay is the lock-free version of delayN. It must be called after advance.
+func (lim *Limiter) delay(now time.Time, n int64) (time.Duration, bool) {
+	if lim.maxCount > 0 && n > lim.maxCount {
+		return 0, false
+	}
+
+	// The threshold considered by AllowN, i.e. of the default priority.
+	delay, ok := lim.countDelay(now, n, lim.priorityLimit(0))
+	if !ok {
+		return 0, false
+	}
+
+	// All events are denied until the cooldown ends.
+	if d := lim.cooldownUntil.Sub(now); d > delay {
+		delay = d
+	}
+	// Once the cap is reached, wait for the next window.
+	if lim.maxCount > 0 && n > lim.maxCount-lim.curr.Count()-lim.smoother.Pending() {
+		if d := lim.aligner.Next(lim.curr.Start()).Sub(now); d > delay {
+			delay = d
+		}
+	}
+	return delay, true
+}
+
+// countDelay returns the duration to wait, starting from time now, before
+// the count permits n more events under limit. It must be called after
+// advance.
+func (lim *Limiter) countDelay(now time.Time, n, limit int64) (time.Duration, bool) {
+	// The smoothed events yet to be settled are counted as if they were
+	// in the current window.
+	pending := lim.smoother.Pending()
+
+	if n > limit {
+		if lim.oversize != AdmitOnce {
+			return 0, false
+		}
+		// Wait for the count to drop to zero.
+		return lim.searchDelay(now, -pending), true
+	}
+	if lim.count(now)+pending+n <= limit {
+		return 0, true
+	}
+
+	if lim.weightFunc != nil {
+		return lim.searchDelay(now, limit-n-pending), true
+	}
+
+	prevCount, currCount := lim.prev.Count(), lim.curr.Count()+pending
+	start := lim.curr.Start()
+	end := lim.aligner.Next(start)
+
+	// Wait for the weight of the previous window to decay enough, if the
+	// events can fit into the current window.
+	if room := limit - currCount - n; room >= 0 && prevCount > 0 {
+		decay := float64(end.Sub(start)) * float64(room) / float64(prevCount)
+		return end.Add(-time.Duration(decay)).Sub(now), true
+	}
+
+	// Otherwise, wait for the current window to become the previous one.
+	room := limit - n
+	if currCount <= room {
+		return end.Sub(now), true
+	}
+	next := lim.aligner.Next(end)
+	decay := float64(next.Sub(end)) * float64(room) / float64(currCount)
+	return next.Add(-time.Duration(decay)).Sub(now), true
+}
+
+// searchDelay finds the delay until the count drops to room by binary
+// search, which works for any non-increasing weight function. It must be
+// called after advance.
+func (lim *Limiter) searchDelay(now time.Time, room int64) time.Duration {
+	// Two windows later, the count will have dropped to zero.
+	end := lim.aligner.Next(lim.curr.Start())
+	lo, hi := time.Duration(0), lim.aligner.Next(end).Sub(now)
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if lim.countAt(now.Add(mid)) <= room {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo
+}
@@ -0,0 +1,251 @@
+package slidingwindow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiter is the method set shared by Limiter and rate.Limiter.
+type rateLimiter interface {
+	Allow() bool
+	Wait(ctx context.Context) error
+}
+
+var (
+	_ rateLimiter = (*Limiter)(nil)
+	_ rateLimiter = (*rate.Limiter)(nil)
+)
+
+func consume(l rateLimiter, n int) (allowed int, err error) {
+	for i := 0; i < n; i++ {
+		if l.Allow() {
+			allowed++
+		}
+	}
+	return allowed, l.Wait(context.Background())
+}
+
+func TestLimiter_RateLimiter(t *testing.T) {
+	cases := []struct {
+		name string
+		lim  rateLimiter
+	}{
+		{
+			"slidingwindow",
+			func() rateLimiter {
+				lim, _ := NewLimiter(100*time.Millisecond, 5, func() (Window, StopFunc) {
+					return NewLocalWindow()
+				})
+				return lim
+			}(),
+		},
+		{
+			"rate",
+			rate.NewLimiter(rate.Every(20*time.Millisecond), 5),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			allowed, err := consume(c.lim, 10)
+			if err != nil {
+				t.Fatalf("consume() err: %v", err)
+			}
+			if allowed != 5 {
+				t.Errorf("allowed = %d, want: 5", allowed)
+			}
+		})
+	}
+}
+
+func TestLimiter_delay(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	// prev-window: [t0, t0 + 1s), count: 5
+	// curr-window: [t10, t10 + 1s), count: 3
+	lim.AllowN(t0, 5)
+	lim.AllowN(t10, 3)
+
+	cases := []struct {
+		t     time.Time
+		n     int64
+		delay time.Duration
+		ok    bool
+	}{
+		{t12, 1, 0, true},
+		{t12, 11, 0, false},
+		// Wait until (5 * weight + 3 + 4) <= 10, i.e. weight <= 3/5.
+		{t12, 4, 2 * d, true},
+		// Wait until the next window, where (3 * weight + 0 + 9) <= 10,
+		// i.e. weight <= 1/3.
+		{t12, 9, 8*d + (2*size)/3, true},
+	}
+	for _, c := range cases {
+		delay, ok := lim.delayN(c.t, c.n)
+		if ok != c.ok || (delay-c.delay).Round(time.Millisecond) != 0 {
+			t.Errorf("lim.delayN(%v, %d) = (%v, %v), want: (%v, %v)",
+				c.t, c.n, delay, ok, c.delay, c.ok)
+		}
+	}
+}
+
+func TestLimiter_WaitN(t *testing.T) {
+	size := 100 * time.Millisecond
+	lim, _ := NewLimiter(size, 10, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	start := time.Now().Truncate(size)
+	lim.AllowN(start, 10)
+
+	// Wait until the next window, where (10 * weight + 1) <= 10,
+	// i.e. weight <= 9/10.
+	begin := time.Now()
+	if err := lim.WaitN(context.Background(), start.Add(size/2), 1); err != nil {
+		t.Fatalf("lim.WaitN() err: %v", err)
+	}
+	if elapsed, want := time.Since(begin), size/2+size/10; elapsed < want {
+		t.Errorf("lim.WaitN() took %v, want: >= %v", elapsed, want)
+	}
+
+	err := lim.WaitN(context.Background(), start, 11)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("lim.WaitN(n=11) err: %v, want: %v", err, ErrLimitExceeded)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := lim.WaitN(ctx, start, 1); err != context.Canceled {
+		t.Errorf("lim.WaitN() err: %v, want: %v", err, context.Canceled)
+	}
+}
+
+func TestLimiter_WaitN_deadline(t *testing.T) {
+	lim, _ := NewLimiter(time.Hour, 10, func() (Window, StopFunc) {
+		return NewLocalWindow()
+	})
+
+	now := time.Now()
+	lim.AllowN(now, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	begin := time.Now()
+	err := lim.WaitN(ctx, now, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("lim.WaitN() err: %v, want: %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(begin); elapsed > 100*time.Millisecond {
+		t.Errorf("lim.WaitN() took %v, want: return at once", elapsed)
+	}
+}
+
+// waitN calls lim.WaitN with a timeout of one second, and returns how long
+// it took.
+func waitN(lim *Limiter, now time.Time, n int64) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	begin := time.Now()
+	err := lim.WaitN(ctx, now, n)
+	return time.Since(begin), err
+}
+
+func TestLimiter_WaitN_cooldown(t *testing.T) {
+	lim, _ := NewLimiter(size, 2, newLocalWindow, WithCooldown(3*d))
+	lim.AllowN(t0, 2)
+	lim.AllowN(t0.Add(9*d), 1) // breached, cooling down until t12
+
+	// The count permits 1 event at t11, but the cooldown does not.
+	now := t0.Add(11 * d)
+	if delay, ok := lim.delayN(now, 1); !ok || delay != d {
+		t.Errorf("lim.delayN() = (%v, %v), want: (%v, true)", delay, ok, d)
+	}
+
+	elapsed, err := waitN(lim, now, 1)
+	if err != nil {
+		t.Fatalf("lim.WaitN() err: %v", err)
+	}
+	if elapsed < d || elapsed > 5*d {
+		t.Errorf("lim.WaitN() took %v, want: about %v", elapsed, d)
+	}
+}
+
+func TestLimiter_WaitN_priorityBands(t *testing.T) {
+	lim, _ := NewLimiter(size, limit, newLocalWindow, WithPriorityBands(map[int]float64{0: 0.5}))
+	lim.AllowN(t0, 5)
+
+	// Wait until (5 * weight + 1) <= 5, i.e. weight <= 4/5.
+	now := t10
+	want := 2 * d
+	if delay, ok := lim.delayN(now, 1); !ok || (delay-want).Round(time.Millisecond) != 0 {
+		t.Errorf("lim.delayN() = (%v, %v), want: (%v, true)", delay, ok, want)
+	}
+
+	elapsed, err := waitN(lim, now, 1)
+	if err != nil {
+		t.Fatalf("lim.WaitN() err: %v", err)
+	}
+	if elapsed < want || elapsed > 2*want {
+		t.Errorf("lim.WaitN() took %v, want: about %v", elapsed, want)
+	}
+
+	// The events beyond the threshold of the default priority are never
+	// permitted.
+	if _, ok := lim.delayN(now, 6); ok {
+		t.Errorf("lim.delayN(n=6) ok = true, want: false")
+	}
+}
+
+func TestLimiter_WaitN_maxCount(t *testing.T) {
+	lim, _ := NewLimiter(size, 100, newLocalWindow, WithMaxCount(10))
+	lim.AllowN(t0, 10)
+
+	// The count permits more events, but the cap does not until the next
+	// window.
+	now := t0.Add(9 * d)
+	if delay, ok := lim.delayN(now, 1); !ok || delay != d {
+		t.Errorf("lim.delayN() = (%v, %v), want: (%v, true)", delay, ok, d)
+	}
+	if _, ok := lim.delayN(now, 11); ok {
+		t.Errorf("lim.delayN(n=11) ok = true, want: false")
+	}
+
+	elapsed, err := waitN(lim, now, 1)
+	if err != nil {
+		t.Fatalf("lim.WaitN() err: %v", err)
+	}
+	if elapsed < d || elapsed > 5*d {
+		t.Errorf("lim.WaitN() took %v, want: about %v", elapsed, d)
+	}
+}
+
+func TestLimiter_WaitN_smoothing(t *testing.T) {
+	size := 100 * time.Millisecond
+	lim, _ := NewLimiter(size, 10, newLocalWindow, WithSmoothing(size/2))
+
+	start := time.Now().Truncate(size)
+	lim.AllowN(start, 5)
+
+	// At +10ms, 1 event has been settled and 4 are pending. Wait until the
+	// next window, where (5 * weight + 6) <= 10, i.e. weight <= 4/5.
+	now := start.Add(size / 10)
+	want := size + size/10
+	if delay, ok := lim.delayN(now, 6); !ok || (delay-want).Round(time.Millisecond) != 0 {
+		t.Errorf("lim.delayN() = (%v, %v), want: (%v, true)", delay, ok, want)
+	}
+
+	elapsed, err := waitN(lim, now, 6)
+	if err != nil {
+		t.Fatalf("lim.WaitN() err: %v", err)
+	}
+	if elapsed < want || elapsed > 2*want {
+		t.Errorf("lim.WaitN() took %v, want: about %v", elapsed, want)
+	}
+}
@@ -0,0 +1,60 @@
+package slidingwindow
+
+import (
+	"context"
+	"time"
+)
+
+// sharedGetter is implemented by synchronizers which can get the count from
+// the central datastore.
+type sharedGetter interface {
+	getShared(key string, start int64) (int64, error)
+}
+
+// windowWarmer is implemented by windows which can fetch the counts shared
+// across nodes on demand, where the state of the window is only accessed
+// within locked. It returns the shared count of the previous window along
+// with whether it is known.
+type windowWarmer interface {
+	warm(ctx context.Context, prevStart time.Time, locked func(f func())) (prevCount int64, ok bool, err error)
+}
+
+// Warm advances the limiter to time now, and then synchronously fetches
+// the shared counts of both the current and the previous windows from the
+// central datastore, which is useful for a node on startup to not over-admit
+// before its first sync. It should be called before serving any requests.
+// For a window without sync behaviour (e.g. LocalWindow), Warm is a no-op.
+//
+// Like Flush, the lock of the limiter is not held during the exchange with
+// the central datastore.
+func (lim *Limiter) Warm(ctx context.Context, now time.Time) error {
+	lim.mu.Lock()
+	if lim.closed {
+		lim.mu.Unlock()
+		return ErrClosed
+	}
+	w, ok := lim.curr.(windowWarmer)
+	if !ok {
+		lim.mu.Unlock()
+		return nil
+	}
+	lim.advance(now)
+	prevStart := lim.prev.Start()
+	lim.gen++
+	lim.mu.Unlock()
+
+	prevCount, ok, err := w.warm(ctx, prevStart, lim.locked)
+	if err != nil || !ok {
+		return err
+	}
+
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	// The windows may have moved along in the meantime.
+	if lim.prev.Start().Equal(prevStart) {
+		lim.prev.Reset(prevStart, prevCount)
+		lim.gen++
+	}
+	return nil
+}
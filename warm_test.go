@@ -0,0 +1,66 @@
+package slidingwindow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Warm(t *testing.T) {
+	newSyncers := map[string]func(Datastore) Synchronizer{
+		"blocking": func(store Datastore) Synchronizer {
+			return NewBlockingSynchronizer(store, time.Hour)
+		},
+		"nonblocking": func(store Datastore) Synchronizer {
+			return NewNonblockingSynchronizer(store, time.Hour)
+		},
+	}
+	for name, newSyncer := range newSyncers {
+		newSyncer := newSyncer
+		t.Run(name, func(t *testing.T) {
+			// The counts shared by the other nodes.
+			store := newMemDatastore()
+			store.Add("test", t0.UnixNano(), 40)  // nolint:errcheck
+			store.Add("test", t10.UnixNano(), 30) // nolint:errcheck
+
+			lim, stop := NewLimiter(size, 100, func() (Window, StopFunc) {
+				return NewSyncWindow("test", newSyncer(store))
+			})
+			defer stop()
+
+			if err := lim.Warm(context.Background(), t12); err != nil {
+				t.Fatalf("lim.Warm() err: %v", err)
+			}
+			if got := lim.CurrentWindowForTest().Count(); got != 30 {
+				t.Errorf("lim.CurrentWindowForTest().Count() = %d, want: 30", got)
+			}
+			if got := lim.PreviousWindowForTest().Count(); got != 40 {
+				t.Errorf("lim.PreviousWindowForTest().Count() = %d, want: 40", got)
+			}
+			// 40 * 8/10 + 30 = 62.
+			if got := lim.Count(t12); got != 62 {
+				t.Errorf("lim.Count(%v) = %d, want: 62", t12, got)
+			}
+		})
+	}
+}
+
+func TestLimiter_Warm_Error(t *testing.T) {
+	wantErr := errors.New("unavailable")
+	lim, _ := NewLimiter(time.Hour, 100, func() (Window, StopFunc) {
+		return NewSyncWindow("test", NewBlockingSynchronizer(errDatastore{wantErr}, time.Hour))
+	})
+	if err := lim.Warm(context.Background(), t0); !errors.Is(err, wantErr) {
+		t.Errorf("lim.Warm() err: %v, want: %v", err, wantErr)
+	}
+
+	local, stop := NewLimiter(time.Hour, 100, newLocalWindow)
+	if err := local.Warm(context.Background(), t0); err != nil {
+		t.Errorf("local.Warm() err: %v, want: nil", err)
+	}
+	stop()
+	if err := local.Warm(context.Background(), t0); err != ErrClosed {
+		t.Errorf("local.Warm() err: %v, want: %v", err, ErrClosed)
+	}
+}
@@ -1,6 +1,7 @@
 package slidingwindow
 
 import (
+	"context"
 	"time"
 )
 
@@ -44,6 +45,8 @@ type (
 		Start   int64
 		Count   int64
 		Changes int64
+		// The reset epoch last observed, or -1 if none.
+		Epoch int64
 	}
 
 	SyncResponse struct {
@@ -54,6 +57,9 @@ type (
 		Changes int64
 		// The total changes accumulated by all the other limiters.
 		OtherChanges int64
+		// The current reset epoch, which is always 0 if the datastore
+		// does not support coordinated resets.
+		Epoch int64
 	}
 
 	MakeFunc   func() SyncRequest
@@ -80,21 +86,60 @@ type SyncWindow struct {
 	LocalWindow
 	changes int64
 
+	// The reset epoch last observed, which is valid only if epochSeen.
+	epoch     int64
+	epochSeen bool
+
 	key    string
 	syncer Synchronizer
+
+	lagThreshold time.Duration
+	lagHook      func(lag time.Duration)
+	lagFired     bool // Whether the hook has fired for the stall.
+	// Since when the changes have been unsynced, and since when the changes
+	// made after the latest sync request (i.e. more than requested) have.
+	pendingSince time.Time
+	laterSince   time.Time
+	requested    int64
+}
+
+// SyncWindowOption configures optional behaviours of a SyncWindow.
+type SyncWindowOption func(*SyncWindow)
+
+// WithSyncLagHook sets the hook which is called once the local changes have
+// been waiting to be synced for longer than threshold, e.g. due to a wedged
+// synchronizer or a slow datastore. The hook fires once per stall, and again
+// only after the changes are synced.
+//
+// The hook is called synchronously with the limiter's lock held, thus it
+// must not call the limiter's methods.
+func WithSyncLagHook(threshold time.Duration, hook func(lag time.Duration)) SyncWindowOption {
+	return func(w *SyncWindow) {
+		w.lagThreshold = threshold
+		w.lagHook = hook
+	}
 }
 
 // NewSyncWindow creates an instance of SyncWindow with the given synchronizer.
-func NewSyncWindow(key string, syncer Synchronizer) (*SyncWindow, StopFunc) {
+func NewSyncWindow(key string, syncer Synchronizer, opts ...SyncWindowOption) (*SyncWindow, StopFunc) {
 	w := &SyncWindow{
 		key:    key,
 		syncer: syncer,
 	}
+	for _, opt := range opts {
+		opt(w)
+	}
 
 	w.syncer.Start()
 	return w, w.syncer.Stop
 }
 
+func (w *SyncWindow) setLogger(l Logger) {
+	if ls, ok := w.syncer.(loggerSetter); ok {
+		ls.setLogger(l)
+	}
+}
+
 func (w *SyncWindow) AddCount(n int64) {
 	w.changes += n
 	w.LocalWindow.AddCount(n)
@@ -107,16 +152,26 @@ func (w *SyncWindow) Reset(s time.Time, c int64) {
 	// central datastore before the reset, thus let the periodic synchronization
 	// take full charge of the accuracy of the window's count.
 	w.changes = 0
+	w.pendingSince, w.laterSince, w.lagFired = time.Time{}, time.Time{}, false
+	w.requested = 0
 
 	w.LocalWindow.Reset(s, c)
 }
 
 func (w *SyncWindow) makeSyncRequest() SyncRequest {
+	// All the changes by now are sent along with the request.
+	w.requested, w.laterSince = w.changes, time.Time{}
+
+	epoch := int64(-1)
+	if w.epochSeen {
+		epoch = w.epoch
+	}
 	return SyncRequest{
 		Key:     w.key,
 		Start:   w.LocalWindow.start,
 		Count:   w.LocalWindow.count,
 		Changes: w.changes,
+		Epoch:   epoch,
 	}
 }
 
@@ -125,14 +180,155 @@ func (w *SyncWindow) handleSyncResponse(resp SyncResponse) {
 		// Update the state of the window, only when it has not been reset
 		// during the latest sync.
 
+		if w.epochSeen && resp.Epoch < w.epoch {
+			// The response is stale, since it was made before a reset.
+			return
+		}
+
 		// Take the changes accumulated by other limiters into consideration.
 		w.LocalWindow.count += resp.OtherChanges
 
 		// Subtract the amount that has been synced from existing changes.
 		w.changes -= resp.Changes
+
+		if w.epochSeen && resp.Epoch != w.epoch {
+			// Another node has reset the shared count, so drop all
+			// the remaining changes made before the reset.
+			w.LocalWindow.count -= w.changes
+			w.changes = 0
+		}
+		w.epoch, w.epochSeen = resp.Epoch, true
+
+		// The remaining changes were made after the request.
+		w.pendingSince, w.laterSince, w.lagFired = w.laterSince, time.Time{}, false
+		w.requested = 0
 	}
 }
 
 func (w *SyncWindow) Sync(now time.Time) {
+	if w.lagHook != nil {
+		w.checkLag(now)
+	}
 	w.syncer.Sync(now, w.makeSyncRequest, w.handleSyncResponse)
 }
+
+// checkLag fires the lag hook if the changes have been unsynced for too long.
+func (w *SyncWindow) checkLag(now time.Time) {
+	if w.changes == 0 {
+		w.pendingSince, w.laterSince, w.lagFired = time.Time{}, time.Time{}, false
+		return
+	}
+	if w.pendingSince.IsZero() {
+		w.pendingSince = now
+	}
+	if w.laterSince.IsZero() && w.changes > w.requested {
+		w.laterSince = now
+	}
+	if lag := now.Sub(w.pendingSince); lag > w.lagThreshold && !w.lagFired {
+		w.lagFired = true
+		w.lagHook(lag)
+	}
+}
+
+// resetShared resets the count of the window, across all nodes sharing
+// the central datastore, where the state of the window is only accessed
+// within locked, which runs f with the window's lock held.
+func (w *SyncWindow) resetShared(locked func(f func())) error {
+	r, ok := w.syncer.(sharedResetter)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	var start int64
+	locked(func() { start = w.LocalWindow.start })
+	epoch, err := r.resetShared(w.key, start)
+	if err != nil {
+		return err
+	}
+
+	locked(func() {
+		if w.LocalWindow.start != start {
+			// The window has moved along in the meantime.
+			return
+		}
+		w.LocalWindow.count, w.changes = 0, 0
+		w.epoch, w.epochSeen = epoch, true
+	})
+	return nil
+}
+
+// warm syncs the window, and then returns the shared count of the window
+// represented by prevStart, i.e. the previous one.
+func (w *SyncWindow) warm(ctx context.Context, prevStart time.Time, locked func(f func())) (int64, bool, error) {
+	if err := w.flush(ctx, locked); err != nil {
+		return 0, false, err
+	}
+	g, ok := w.syncer.(sharedGetter)
+	if !ok {
+		return 0, false, nil
+	}
+	count, err := g.getShared(w.key, prevStart.UnixNano())
+	return count, err == nil, err
+}
+
+// seedLocal sets the count of the window, while keeping the changes which
+// have not been synced yet.
+func (w *SyncWindow) seedLocal(count int64) {
+	w.LocalWindow.count = count
+}
+
+// seedShared seeds the window with count, where the state of the window is
+// only accessed within locked. The window first catches up with the shared
+// count, and only the rest of count is then synced as a change.
+func (w *SyncWindow) seedShared(count int64, locked func(f func())) error {
+	g, ok := w.syncer.(sharedGetter)
+	if !ok {
+		// The shared count is unknown, thus sync the whole count.
+		locked(func() { w.AddCount(count - w.LocalWindow.count) })
+		return nil
+	}
+
+	var start int64
+	locked(func() { start = w.LocalWindow.start })
+	shared, err := g.getShared(w.key, start)
+	if err != nil {
+		return err
+	}
+
+	locked(func() {
+		if w.LocalWindow.start != start {
+			// The window has moved along in the meantime.
+			return
+		}
+		w.LocalWindow.count = shared + w.changes
+		if delta := count - w.LocalWindow.count; delta > 0 {
+			w.AddCount(delta)
+		}
+	})
+	return nil
+}
+
+// Flush immediately syncs the changes accumulated within the window to the
+// central datastore. It's a no-op if the synchronizer does not implement
+// SyncFlusher.
+func (w *SyncWindow) Flush(ctx context.Context) error {
+	return w.flush(ctx, func(f func()) { f() })
+}
+
+// flush is like Flush, but the state of the window is only accessed within
+// locked, which runs f with the window's lock held.
+func (w *SyncWindow) flush(ctx context.Context, locked func(f func())) error {
+	f, ok := w.syncer.(SyncFlusher)
+	if !ok {
+		return nil
+	}
+
+	makeReq := func() (req SyncRequest) {
+		locked(func() { req = w.makeSyncRequest() })
+		return req
+	}
+	handleResp := func(resp SyncResponse) {
+		locked(func() { w.handleSyncResponse(resp) })
+	}
+	return f.Flush(ctx, makeReq, handleResp)
+}